@@ -2,34 +2,224 @@ package main
 
 import (
 	"os"
+	"regexp"
+	"sync"
 	"time"
 
+	u "github.com/cilium-team/docker-collector/utils"
 	uc "github.com/cilium-team/docker-collector/utils/comm"
 	ucdb "github.com/cilium-team/docker-collector/utils/comm/db"
 )
 
 type ContainersRegistry struct {
-	DB   ucdb.Db
-	Node uc.Node
+	DB         ucdb.Db
+	Node       uc.Node
+	Collectors []uc.Collector
+	Enrichers  []uc.Enricher
+
+	// untracked are the PIDs, by container ID, of the running runtime
+	// containers that aren't registered, because they are filtered out or
+	// their start is still pending. It has its own mutex since starts are
+	// recorded before the registry is locked.
+	untracked      map[string]int
+	untrackedMutex sync.Mutex
 }
 
-func NewContainersRegistry(dClient uc.Docker, db ucdb.Db) *ContainersRegistry {
+func NewContainersRegistry(runtime uc.Runtime, db ucdb.Db, collectors ...uc.Collector) *ContainersRegistry {
 	hn, err := os.Hostname()
 	if err != nil {
 		log.Debug("Error while getting the hostname: %v", err)
 	}
 	return &ContainersRegistry{
 		Node: uc.Node{
-			DockerClient: dClient,
-			CreatedAt:    time.Now(),
-			Name:         hn,
+			Runtime:   runtime,
+			CreatedAt: time.Now(),
+			Name:      hn,
 		},
-		DB: db,
+		DB:         db,
+		Collectors: collectors,
+	}
+}
+
+// UpdateDBNode stores the node and the given samples in the database.
+func (c *ContainersRegistry) UpdateDBNode(samples []uc.Sample) error {
+	return c.DB.UpdateNode(&c.Node, samples)
+}
+
+// Prepare fetches the metadata of every enricher for the container with the
+// given ID and labels. It must be called before the containers are locked,
+// since enrichers may query remote services, such as the kubelet.
+func (c *ContainersRegistry) Prepare(dockerID string, labels map[string]string) {
+	for _, enricher := range c.Enrichers {
+		if err := enricher.Prepare(dockerID, labels); err != nil {
+			log.Warning("Error while enriching container %s: %v", dockerID, err)
+		}
+	}
+}
+
+// enrich adds the metadata of every enricher, fetched by Prepare, to the
+// given container.
+func (c *ContainersRegistry) enrich(cont *uc.Container) {
+	for _, enricher := range c.Enrichers {
+		enricher.Enrich(cont)
+	}
+}
+
+// supports returns true if the given collector can read the metrics of the
+// given container. Pseudo containers of network namespaces without process
+// only have network interfaces. The metrics of a network namespace are read
+// from a single container of the namespace and not from host network
+// containers, whose metrics are the ones of the node.
+func supports(collector uc.Collector, cont *uc.Container) bool {
+	if uc.CollectsPerNetNS(collector) && (!cont.NetNSOwner || cont.HostNetwork) {
+		return false
+	}
+	return cont.PID != 0 || collector.Name() == uc.NetworkFamily
+}
+
+// discover runs the discovery of every collector on the given container. The
+// network namespace groups are updated first, so the collectors of the
+// network namespace run if the container owns it, and the host interfaces are
+// read again since the ones of the container, such as its veth peers, are
+// usually new.
+func (c *ContainersRegistry) discover(cont *uc.Container) {
+	c.Node.UpdateNetNSGroups()
+	c.updateHostLinks()
+	for _, collector := range c.Collectors {
+		if !supports(collector, cont) {
+			continue
+		}
+		if err := collector.Discover(cont); err != nil {
+			log.Debug("Error while discovering %s metrics of container %s: %v", collector.Name(), cont.DockerID, err)
+		}
+	}
+}
+
+// updateHostLinks reads the host interfaces shared by the collectors of all
+// containers.
+func (c *ContainersRegistry) updateHostLinks() {
+	if err := c.Node.UpdateHostLinks(); err != nil {
+		log.Debug("Unable to read host interfaces: %v", err)
+	}
+}
+
+// Collect reads the metrics of all active containers, and of the node itself,
+// with every collector.
+func (c *ContainersRegistry) Collect() []uc.Sample {
+	var samples []uc.Sample
+	c.Node.UpdateNetNSGroups()
+	c.updateHostLinks()
+	for i := range c.Node.Containers {
+		cont := &c.Node.Containers[i]
+		if !cont.IsActive {
+			continue
+		}
+		for _, collector := range c.Collectors {
+			if !supports(collector, cont) {
+				continue
+			}
+			s, err := collector.Collect(cont)
+			if u.IsPermission(err) {
+				// Containers of other users can't be read when not
+				// running privileged.
+				log.Debug("Skipping %s metrics of container %s: %v", collector.Name(), cont.DockerID, err)
+				continue
+			}
+			if err != nil {
+				log.Error("Error while collecting %s metrics of container %s: %v", collector.Name(), cont.DockerID, err)
+				continue
+			}
+			samples = append(samples, s...)
+		}
+	}
+	for _, collector := range c.Collectors {
+		nc, ok := collector.(uc.NodeCollector)
+		if !ok {
+			continue
+		}
+		s, err := nc.CollectNode(&c.Node)
+		if err != nil {
+			log.Error("Error while collecting %s metrics of node %s: %v", collector.Name(), c.Node.Name, err)
+			continue
+		}
+		samples = append(samples, s...)
+	}
+	return samples
+}
+
+// SetUntracked records the PID of a running runtime container that isn't
+// registered, so its network namespace isn't mistaken for one that doesn't
+// belong to a container.
+func (c *ContainersRegistry) SetUntracked(dockerID string, pid int) {
+	c.untrackedMutex.Lock()
+	defer c.untrackedMutex.Unlock()
+	if c.untracked == nil {
+		c.untracked = map[string]int{}
+	}
+	c.untracked[dockerID] = pid
+}
+
+// RemoveUntracked forgets the PID of a runtime container that is now
+// registered or no longer running.
+func (c *ContainersRegistry) RemoveUntracked(dockerID string) {
+	c.untrackedMutex.Lock()
+	defer c.untrackedMutex.Unlock()
+	delete(c.untracked, dockerID)
+}
+
+// untrackedPIDs returns the PIDs of the running runtime containers that
+// aren't registered.
+func (c *ContainersRegistry) untrackedPIDs() []int {
+	c.untrackedMutex.Lock()
+	defer c.untrackedMutex.Unlock()
+	pids := make([]int, 0, len(c.untracked))
+	for _, pid := range c.untracked {
+		pids = append(pids, pid)
 	}
+	return pids
 }
 
-func (c *ContainersRegistry) UpdateDBNode() error {
-	return c.DB.UpdateNode(&c.Node)
+// DiscoverNetNS registers the network namespaces that don't belong to
+// containers as pseudo containers, except the ones whose name matches skip,
+// and removes the pseudo containers of namespaces that no longer exist. The
+// namespaces of untracked runtime containers aren't registered.
+func (c *ContainersRegistry) DiscoverNetNS(skip *regexp.Regexp) error {
+	found, err := uc.DiscoverNetNS(c.Node.Containers, c.untrackedPIDs())
+	if err != nil {
+		return err
+	}
+	changed := false
+	ids := map[string]bool{}
+	for _, pseudo := range found {
+		if skip != nil && skip.MatchString(pseudo.Name) {
+			continue
+		}
+		ids[pseudo.DockerID] = true
+		if i := c.GetSliceIndex(pseudo.DockerID); i != -1 {
+			cont := &c.Node.Containers[i]
+			if cont.PID != pseudo.PID {
+				cont.PID = pseudo.PID
+				c.discover(cont)
+			}
+			continue
+		}
+		log.Info("Network namespace '%s' added to audit", pseudo.Name)
+		c.Node.Containers = append(c.Node.Containers, pseudo)
+		c.discover(&c.Node.Containers[len(c.Node.Containers)-1])
+		changed = true
+	}
+	for i := len(c.Node.Containers) - 1; i >= 0; i-- {
+		cont := &c.Node.Containers[i]
+		if cont.IsNetNS() && !ids[cont.DockerID] {
+			log.Info("Network namespace '%s' removed from audit", cont.Name)
+			c.Node.Containers = append(c.Node.Containers[:i], c.Node.Containers[i+1:]...)
+			changed = true
+		}
+	}
+	if changed {
+		return c.UpdateDBNode(nil)
+	}
+	return nil
 }
 
 func (c *ContainersRegistry) GetSliceIndex(dockerID string) int {
@@ -40,12 +230,21 @@ func (c *ContainersRegistry) Create(dockerID string) error {
 	if err := c.Node.Create(dockerID); err != nil {
 		return err
 	}
-	return c.DB.UpdateNode(&c.Node)
+	if i := c.GetSliceIndex(dockerID); i != -1 {
+		// Containers are only created on start, without
+		// containersMutex held, so the enrichers are prepared here.
+		c.Prepare(dockerID, c.Node.Containers[i].DockerLabels)
+		c.enrich(&c.Node.Containers[i])
+		if c.Node.Containers[i].IsActive {
+			c.discover(&c.Node.Containers[i])
+		}
+	}
+	return c.DB.UpdateNode(&c.Node, nil)
 }
 
 func (c *ContainersRegistry) DeleteByIndex(i int) {
 	c.Node.Containers = append(c.Node.Containers[:i], c.Node.Containers[i+1:]...)
-	c.DB.UpdateNode(&c.Node)
+	c.DB.UpdateNode(&c.Node, nil)
 }
 
 func (c *ContainersRegistry) DeleteByDockerId(dockerID string) {
@@ -56,6 +255,10 @@ func (c *ContainersRegistry) DeleteByDockerId(dockerID string) {
 
 func (c *ContainersRegistry) Activate(dockerID, dockerPID string) {
 	c.Node.Activate(dockerID, dockerPID)
+	if i := c.GetSliceIndex(dockerID); i != -1 {
+		c.enrich(&c.Node.Containers[i])
+		c.discover(&c.Node.Containers[i])
+	}
 }
 
 func (c *ContainersRegistry) Deactivate(dockerID string) bool {
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+type fakeRuntime struct {
+	containers map[string]*uc.RuntimeContainer
+}
+
+func (r *fakeRuntime) Name() string { return "fake" }
+func (r *fakeRuntime) Ping() error  { return nil }
+
+func (r *fakeRuntime) ListContainers() ([]uc.RuntimeContainer, error) {
+	var containers []uc.RuntimeContainer
+	for _, rc := range r.containers {
+		containers = append(containers, *rc)
+	}
+	return containers, nil
+}
+
+func (r *fakeRuntime) InspectContainer(id string) (*uc.RuntimeContainer, error) {
+	if rc, ok := r.containers[id]; ok {
+		return rc, nil
+	}
+	return nil, errors.New("no such container")
+}
+
+func (r *fakeRuntime) MonitorEvents(events chan<- uc.RuntimeEvent) error { return nil }
+
+// fakeDb records the samples of every UpdateNode call.
+type fakeDb struct {
+	updates [][]uc.Sample
+}
+
+func (db *fakeDb) Close() {}
+
+func (db *fakeDb) UpdateNode(node *uc.Node, samples []uc.Sample) error {
+	db.updates = append(db.updates, samples)
+	return nil
+}
+
+func (db *fakeDb) CreateNode(node *uc.Node) error { return nil }
+func (db *fakeDb) CreateCluster() error           { return nil }
+
+// fakeCollector returns a sample per container, valued after its number of
+// discoveries.
+type fakeCollector struct {
+	discovered map[string]int
+}
+
+func (c *fakeCollector) Name() string { return "fake" }
+
+func (c *fakeCollector) Discover(cont *uc.Container) error {
+	c.discovered[cont.DockerID]++
+	return nil
+}
+
+func (c *fakeCollector) Collect(cont *uc.Container) ([]uc.Sample, error) {
+	return []uc.Sample{{
+		Family:    "fake",
+		Name:      "discoveries",
+		Kind:      uc.Gauge,
+		Value:     int64(c.discovered[cont.DockerID]),
+		Container: cont,
+	}}, nil
+}
+
+func TestContainersRegistry(t *testing.T) {
+	runtime := &fakeRuntime{containers: map[string]*uc.RuntimeContainer{
+		"running": {ID: "running", Name: "/running", PID: 1, Running: true},
+		"stopped": {ID: "stopped", Name: "/stopped"},
+	}}
+	db := &fakeDb{}
+	collector := &fakeCollector{discovered: map[string]int{}}
+	c := NewContainersRegistry(runtime, db, collector)
+
+	for _, id := range []string{"running", "stopped"} {
+		if err := c.Create(id); err != nil {
+			t.Fatalf("error while creating container %s: %s", id, err)
+		}
+	}
+	if err := c.Create("missing"); err == nil {
+		t.Errorf("creating a missing container should return an error")
+	}
+	if len(db.updates) != 2 || db.updates[0] != nil || db.updates[1] != nil {
+		t.Errorf("invalid node updates on creation:\ngot  %v\nwant 2 updates without samples", db.updates)
+	}
+	// Containers that aren't running when created are inactive and not
+	// discovered until they are started.
+	if got := c.ActiveContainers(); got != 1 {
+		t.Errorf("invalid number of active containers:\ngot  %d\nwant %d", got, 1)
+	}
+	if collector.discovered["running"] != 1 || collector.discovered["stopped"] != 0 {
+		t.Errorf("invalid discoveries:\ngot  %v\nwant %s", collector.discovered, "running:1")
+	}
+
+	samples := c.Collect()
+	if len(samples) != 1 || samples[0].Container.DockerID != "running" {
+		t.Fatalf("invalid samples:\ngot  %+v\nwant a sample of container %s", samples, "running")
+	}
+	if err := c.UpdateDBNode(samples); err != nil {
+		t.Fatalf("error while updating node: %s", err)
+	}
+	if last := db.updates[len(db.updates)-1]; len(last) != 1 || last[0].Value != 1 {
+		t.Errorf("invalid samples stored:\ngot  %+v\nwant %+v", last, samples)
+	}
+
+	c.Activate("stopped", "1")
+	c.Deactivate("running")
+	samples = c.Collect()
+	if len(samples) != 1 || samples[0].Container.DockerID != "stopped" || samples[0].Value != 1 {
+		t.Errorf("invalid samples after start and stop:\ngot  %+v\nwant a sample of container %s", samples, "stopped")
+	}
+
+	c.DeleteByDockerId("running")
+	if c.GetSliceIndex("running") != -1 || len(c.Node.Containers) != 1 {
+		t.Errorf("container %s wasn't deleted", "running")
+	}
+}
+
+func TestContainersRegistryUntracked(t *testing.T) {
+	c := NewContainersRegistry(&fakeRuntime{}, &fakeDb{})
+	c.SetUntracked("filtered", 10)
+	c.SetUntracked("pending", 20)
+	c.SetUntracked("pending", 21)
+	pids := c.untrackedPIDs()
+	sort.Ints(pids)
+	if len(pids) != 2 || pids[0] != 10 || pids[1] != 21 {
+		t.Errorf("invalid untracked PIDs:\ngot  %v\nwant %v", pids, []int{10, 21})
+	}
+	c.RemoveUntracked("pending")
+	c.RemoveUntracked("unknown")
+	if pids := c.untrackedPIDs(); len(pids) != 1 || pids[0] != 10 {
+		t.Errorf("invalid untracked PIDs after removal:\ngot  %v\nwant %v", pids, []int{10})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,25 +14,39 @@ import (
 	ucdb "github.com/cilium-team/docker-collector/utils/comm/db"
 
 	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/github.com/op/go-logging"
-	d "github.com/cilium-team/docker-collector/Godeps/_workspace/src/github.com/samalba/dockerclient"
 )
 
 var (
-	logLevel      string
-	refreshTime   uint64
-	dbDriver      string
-	skipRegFilter string
-	indexName     string
-	configPath    string
-	log           = logging.MustGetLogger("docker-collector")
+	logLevel       string
+	refreshTime    uint64
+	dbDriver       string
+	skipRegFilter  string
+	labelFilter    string
+	kubeletURL     string
+	runtimeName    string
+	netnsDiscovery bool
+	indexName      string
+	configPath     string
+	metrics        string
+	collectors     []uc.Collector
+	enrichers      []uc.Enricher
+	log            = logging.MustGetLogger("docker-collector")
 )
 
-func init() {
+// setup parses and validates the command line flags. It isn't run as init so
+// the package can be tested.
+func setup() {
 	flag.StringVar(&skipRegFilter, "f", "", "Regex option to prevent docker-collector from reading on those containers that are matched by the given regex. Example: docker-collector -f docker-*")
+	flag.StringVar(&labelFilter, "L", "", "Regex of the container label keys copied to every document. Example: docker-collector -L '^(app|team)$'")
+	flag.StringVar(&kubeletURL, "k", "", "URL of the local kubelet API used to add Kubernetes pod metadata to every document. Example: docker-collector -k http://127.0.0.1:10255")
+	flag.StringVar(&runtimeName, "r", uc.DockerRuntimeName, "Container runtime to monitor, valid options are ("+uc.Runtimes+")")
+	flag.BoolVar(&netnsDiscovery, "N", false, "Also monitor the network namespaces that don't belong to containers, such as the ones of 'ip netns' and of systemd-nspawn machines")
 	flag.StringVar(&logLevel, "l", "info", "Set log level, valid options are (debug|info|warning|error|fatal|panic)")
 	flag.Uint64Var(&refreshTime, "t", 60, "Set refresh time (in seconds) to retrieve statistics from containers")
 	flag.StringVar(&dbDriver, "d", "elasticsearch", "Set database driver to store statistics, valid options are ("+ucdb.DBDrivers+")")
 	flag.StringVar(&indexName, "i", "docker-collector", "Use a specific the prefix of the index name for elasticsearch. Suffix is -YYYY-MM-DD")
+	flag.StringVar(&metrics, "m", uc.DefaultCollectors, "Comma separated list of metric families to collect, valid options are ("+uc.Collectors+")")
+	flag.IntVar(&uc.TopN, "n", uc.TopN, "Number of top entries, such as remote peers or processes, reported per container")
 	flag.StringVar(&configPath, "c", "/docker-collector/configs", "Directory path for kibana configuration and or templates. Configuration filename: 'configs.json', template filename: 'templates.json'")
 	flag.Parse()
 	setupLOG()
@@ -44,6 +59,31 @@ func init() {
 		log.Fatalf("Invalid database driver. Valid options are: \"%s\"", ucdb.DBDrivers)
 		return
 	}
+	if labelFilter != "" {
+		var err error
+		if uc.LabelFilter, err = regexp.Compile(labelFilter); err != nil {
+			log.Fatalf("Invalid label filter: %s", err)
+			return
+		}
+	}
+	if kubeletURL != "" {
+		enricher, err := uc.NewKubernetesEnricher(kubeletURL)
+		if err != nil {
+			log.Fatalf("Invalid kubelet configuration: %s", err)
+			return
+		}
+		enrichers = append(enrichers, enricher)
+	}
+	cgroupMode := u.DetectCgroupMode()
+	log.Info("Using cgroup %s hierarchy", cgroupMode)
+	for _, name := range strings.Split(metrics, ",") {
+		collector, err := uc.NewCollectorOf(strings.TrimSpace(name), cgroupMode)
+		if err != nil {
+			log.Fatalf("%s. Valid options are: \"%s\"", err, uc.Collectors)
+			return
+		}
+		collectors = append(collectors, collector)
+	}
 }
 
 func setupLOG() {
@@ -74,6 +114,7 @@ func setupLOG() {
 var containersMutex = &sync.Mutex{}
 
 func main() {
+	setup()
 	db, err := ucdb.NewConnOf(dbDriver, indexName, configPath)
 	if err != nil {
 		log.Error("Error: %s", err)
@@ -84,22 +125,22 @@ func main() {
 		return
 	}
 	defer db.Close()
-	dockerS, err := uc.NewDockerClientSamalba()
+	runtime, err := uc.NewRuntimeOf(runtimeName)
 	if err != nil {
 		log.Error("Error: %s", err)
 		return
 	}
 
-	log.Info("Trying to get docker client info")
+	log.Info("Trying to get %s runtime info", runtime.Name())
 	wait := 1 * time.Second
 	retries := 10
 	for {
 		log.Info("Attempt %d...", 11-retries)
-		if _, err := dockerS.Info(); err == nil {
+		if err := runtime.Ping(); err == nil {
 			break
 		}
 		if retries < 0 {
-			log.Error("Unable to monitor for events on the given docker client")
+			log.Error("Unable to monitor for events on the given container runtime")
 			return
 		}
 		time.Sleep(wait)
@@ -108,34 +149,35 @@ func main() {
 	}
 	log.Info("Connection successful")
 
-	docker, err := uc.NewDockerClient()
+	containers := NewContainersRegistry(runtime, db, collectors...)
+	containers.Enrichers = enrichers
+
+	runtimeContainers, err := runtime.ListContainers()
 	if err != nil {
 		log.Error("Error: %s", err)
 		return
 	}
 
-	containers := NewContainersRegistry(docker, db)
-
-	dockerAPIContainers, err := dockerS.ListContainers(true, false, "")
-	if err != nil {
+	events := make(chan uc.RuntimeEvent)
+	if err := runtime.MonitorEvents(events); err != nil {
 		log.Error("Error: %s", err)
 		return
 	}
+	go listenForEvents(events, runtime, containers)
 
-	dockerS.StartMonitorEvents(listenForEvents, nil, docker, containers)
-
-	for _, dockerContainer := range dockerAPIContainers {
+	for _, runtimeContainer := range runtimeContainers {
 		matches := false
 		if skipRegFilter != "" {
-			for _, cName := range dockerContainer.Names {
-				if match, _ := regexp.MatchString(skipRegFilter, cName); match {
-					matches = true
-					break
-				}
+			if match, _ := regexp.MatchString(skipRegFilter, runtimeContainer.Name); match {
+				matches = true
 			}
 		}
 		if !matches {
-			containers.Create(dockerContainer.Id)
+			containers.Create(runtimeContainer.ID)
+		} else if netnsDiscovery {
+			if rc, err := runtime.InspectContainer(runtimeContainer.ID); err == nil && rc.Running {
+				containers.SetUntracked(rc.ID, rc.PID)
+			}
 		}
 	}
 
@@ -143,10 +185,8 @@ func main() {
 
 	//Discard first reading
 	containersMutex.Lock()
-	readContainers(docker, containers)
-	for _, cont := range containers.Node.Containers {
-		cont.UpdateLastValue()
-	}
+	discoverNetNS(containers)
+	containers.Collect()
 	if err := db.CreateCluster(); err != nil {
 		log.Error("error while creating cluster for kibana: %+v", err)
 	}
@@ -158,9 +198,9 @@ func main() {
 	for {
 		timeToProcess1 := time.Now()
 		containersMutex.Lock()
-		if containers.ActiveContainers() != 0 {
-			readContainers(docker, containers)
-			if err := containers.UpdateDBNode(); err != nil {
+		discoverNetNS(containers)
+		if samples := containers.Collect(); len(samples) != 0 {
+			if err := containers.UpdateDBNode(samples); err != nil {
 				log.Error("Error while updating node: %v", err)
 			}
 		}
@@ -170,77 +210,75 @@ func main() {
 	}
 }
 
-func readContainers(docker uc.Docker, containers *ContainersRegistry) {
-	for i, container := range containers.Node.Containers {
-		if container.IsActive {
-			containers.Node.Containers[i].UpdateNetInterfaces()
-			for _, netInterface := range container.NetworkInterfaces {
-				if netInterface.IsActive {
-					for j, networkStat := range netInterface.NetworkStats {
-						intpath := uc.BuildNetworkIntPath(netInterface.Name, networkStat.Name)
-						value, err := u.ReadContainerFromProc(container.PID, intpath)
-						if err != nil {
-							value = "0"
-						}
-						intVal, err := strconv.ParseInt(value, 10, 64)
-						if err != nil {
-							log.Error("Error while formating value '%s' to int", value)
-						}
-						netInterface.NetworkStats[j].ValueRead = intVal
-						log.Debug("Container: %s %s/%s: %s", container.DockerID, netInterface.Name, networkStat.Name, value)
-					}
-				}
-			}
-		}
+// discoverNetNS registers the network namespaces that don't belong to
+// containers, if enabled.
+func discoverNetNS(containers *ContainersRegistry) {
+	if !netnsDiscovery {
+		return
+	}
+	var skip *regexp.Regexp
+	if skipRegFilter != "" {
+		skip, _ = regexp.Compile(skipRegFilter)
+	}
+	if err := containers.DiscoverNetNS(skip); err != nil {
+		log.Error("Error while discovering network namespaces: %v", err)
 	}
 }
 
-func listenForEvents(event *d.Event, ec chan error, args ...interface{}) {
-	if event != nil {
-		docker := args[0].(uc.Docker)
-		containers := args[1].(*ContainersRegistry)
-		go func(event d.Event) {
+func listenForEvents(events <-chan uc.RuntimeEvent, runtime uc.Runtime, containers *ContainersRegistry) {
+	for event := range events {
+		go func(event uc.RuntimeEvent) {
 			log.Debug("Msg received %s", event)
+			var rc *uc.RuntimeContainer
+			if event.Status == uc.EventStart {
+				// The container is untracked until activated, its
+				// network namespace isn't one without container.
+				var err error
+				if rc, err = runtime.InspectContainer(event.ID); err == nil {
+					containers.SetUntracked(event.ID, rc.PID)
+					containers.Prepare(event.ID, rc.Labels)
+				}
+			}
 			containersMutex.Lock()
 			switch event.Status {
-			case "create":
-				break
-			case "start":
-				if dic, err := docker.InspectContainer(event.Id); err == nil {
+			case uc.EventStart:
+				if rc != nil {
 					matches := false
 					if skipRegFilter != "" {
-						if match, _ := regexp.MatchString(skipRegFilter, dic.Name); match {
+						if match, _ := regexp.MatchString(skipRegFilter, rc.Name); match {
 							matches = true
 						}
 					}
 					if !matches {
-						log.Info("Container '%s' added to audit", dic.Name)
-						strpid := strconv.Itoa(dic.State.Pid)
-						containers.Activate(event.Id, strpid)
-						if err := containers.UpdateDBNode(); err != nil {
+						log.Info("Container '%s' added to audit", rc.Name)
+						strpid := strconv.Itoa(rc.PID)
+						containers.Activate(event.ID, strpid)
+						containers.RemoveUntracked(event.ID)
+						if err := containers.UpdateDBNode(nil); err != nil {
 							log.Error("Error while updating node: %v", err)
 						}
 					}
 				}
-			case "stop":
-				if containers.Deactivate(event.Id) {
-					log.Info("Container '%s' paused from audit", event.Id)
-					if err := containers.UpdateDBNode(); err != nil {
+			case uc.EventStop:
+				if containers.Deactivate(event.ID) {
+					log.Info("Container '%s' paused from audit", event.ID)
+					if err := containers.UpdateDBNode(nil); err != nil {
 						log.Error("Error while updating node: %v", err)
 					}
 				}
-			case "destroy":
+			case uc.EventDestroy:
 				fallthrough
-			case "die":
-				if i := containers.GetSliceIndex(event.Id); i != -1 {
+			case uc.EventDie:
+				containers.RemoveUntracked(event.ID)
+				if i := containers.GetSliceIndex(event.ID); i != -1 {
 					log.Info("Container '%s' removed from audit", containers.Node.Containers[i].Name)
 					containers.DeleteByIndex(i)
-					if err := containers.UpdateDBNode(); err != nil {
+					if err := containers.UpdateDBNode(nil); err != nil {
 						log.Error("Error while updating node: %v", err)
 					}
 				}
 			}
 			containersMutex.Unlock()
-		}(*event)
+		}(event)
 	}
 }
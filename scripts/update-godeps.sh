@@ -8,6 +8,8 @@ deps=(\
 "github.com/op/go-logging" \
 "github.com/samalba/dockerclient" \
 "gopkg.in/olivere/elastic.v3" \
+"google.golang.org/grpc" \
+"k8s.io/cri-api/pkg/apis/runtime/v1" \
 )
 
 special_deps=(\
@@ -0,0 +1,231 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	cgroupDefaultRoot = "/sys/fs/cgroup"
+	sysDevBlockPath   = "/sys/dev/block"
+)
+
+// CgroupRoot returns the directory where the cgroup hierarchies are mounted.
+// It can be changed with the CGROUP_ROOT environment variable, for example to
+// /proc/1/root/sys/fs/cgroup when running inside a container.
+func CgroupRoot() string {
+	if root := os.Getenv("CGROUP_ROOT"); root != "" {
+		return strings.TrimRight(root, "/")
+	}
+	return cgroupDefaultRoot
+}
+
+// CgroupMode is the layout of the cgroup hierarchies mounted on the host.
+type CgroupMode int
+
+const (
+	// CgroupV1 has one hierarchy per controller.
+	CgroupV1 CgroupMode = iota
+	// CgroupHybrid has one hierarchy per controller plus the unified
+	// hierarchy, without controllers, mounted in unified/.
+	CgroupHybrid
+	// CgroupV2 only has the unified hierarchy.
+	CgroupV2
+)
+
+func (m CgroupMode) String() string {
+	switch m {
+	case CgroupHybrid:
+		return "hybrid"
+	case CgroupV2:
+		return "v2"
+	default:
+		return "v1"
+	}
+}
+
+// DetectCgroupMode returns the layout of the cgroup hierarchies mounted in
+// CgroupRoot.
+func DetectCgroupMode() CgroupMode {
+	root := CgroupRoot()
+	if _, err := os.Stat(root + "/cgroup.controllers"); err == nil {
+		return CgroupV2
+	}
+	if _, err := os.Stat(root + "/unified/cgroup.controllers"); err == nil {
+		return CgroupHybrid
+	}
+	return CgroupV1
+}
+
+// ReadCgroupPaths returns the cgroup path of each controller of the process
+// with the given pid, as listed in /proc/<pid>/cgroup. The path in the unified
+// hierarchy is keyed by an empty controller name.
+func ReadCgroupPaths(pid int) (map[string]string, error) {
+	path := "/cgroup"
+	f, err := os.Open(ProcPath(pid, path))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	defer f.Close()
+	paths, err := parseCgroupPaths(bufio.NewScanner(f))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	return paths, nil
+}
+
+func parseCgroupPaths(s *bufio.Scanner) (map[string]string, error) {
+	paths := map[string]string{}
+	for s.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		fields := strings.SplitN(s.Text(), ":", 3)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("malformed cgroup line '%s'", s.Text())
+		}
+		for _, controller := range strings.Split(fields[1], ",") {
+			controller = strings.TrimPrefix(controller, "name=")
+			paths[controller] = fields[2]
+		}
+	}
+	return paths, s.Err()
+}
+
+// CgroupDir returns the directory of the cgroup with the given path in the
+// hierarchy of the given controller.
+func CgroupDir(controller, path string) string {
+	return strings.TrimRight(CgroupRoot()+"/"+controller+path, "/")
+}
+
+// UnifiedCgroupDir returns the directory of the cgroup with the given path in
+// the unified hierarchy.
+func UnifiedCgroupDir(mode CgroupMode, path string) string {
+	if mode == CgroupHybrid {
+		return strings.TrimRight(CgroupRoot()+"/unified"+path, "/")
+	}
+	return strings.TrimRight(CgroupRoot()+path, "/")
+}
+
+// ReadCgroupInt64 returns the value of a single value cgroup file. The "max"
+// value of cgroup v2 limits is returned as math.MaxInt64.
+func ReadCgroupInt64(dir, file string) (int64, error) {
+	b, err := ioutil.ReadFile(dir + "/" + file)
+	if err != nil {
+		return 0, err
+	}
+	value := strings.TrimSpace(string(b))
+	if value == "max" {
+		return math.MaxInt64, nil
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+// ReadCgroupInt64s returns the values of a cgroup file with space separated
+// values, such as cpuacct.usage_percpu.
+func ReadCgroupInt64s(dir, file string) ([]int64, error) {
+	b, err := ioutil.ReadFile(dir + "/" + file)
+	if err != nil {
+		return nil, err
+	}
+	var values []int64
+	for _, field := range strings.Fields(string(b)) {
+		intVal, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, intVal)
+	}
+	return values, nil
+}
+
+// ReadCgroupKeyValues returns the values of a flat keyed cgroup file, with one
+// "key value" pair per line, such as cpu.stat or memory.stat.
+func ReadCgroupKeyValues(dir, file string) (map[string]int64, error) {
+	f, err := os.Open(dir + "/" + file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseKeyValues(bufio.NewScanner(f))
+}
+
+func parseKeyValues(s *bufio.Scanner) (map[string]int64, error) {
+	values := map[string]int64{}
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		intVal, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed value in '%s'", s.Text())
+		}
+		values[fields[0]] = intVal
+	}
+	return values, s.Err()
+}
+
+// ReadCgroupDeviceValues returns the values of a cgroup file keyed by device,
+// with one "major:minor key value" entry per line, such as
+// blkio.throttle.io_service_bytes, or one "major:minor key=value..." entry per
+// line, such as io.stat. Values are keyed by device and by key.
+func ReadCgroupDeviceValues(dir, file string) (map[string]map[string]int64, error) {
+	f, err := os.Open(dir + "/" + file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseDeviceValues(bufio.NewScanner(f))
+}
+
+func parseDeviceValues(s *bufio.Scanner) (map[string]map[string]int64, error) {
+	devices := map[string]map[string]int64{}
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 2 || !strings.Contains(fields[0], ":") {
+			// "Total value" lines
+			continue
+		}
+		values, ok := devices[fields[0]]
+		if !ok {
+			values = map[string]int64{}
+			devices[fields[0]] = values
+		}
+		if len(fields) == 3 && !strings.Contains(fields[1], "=") {
+			intVal, err := strconv.ParseInt(fields[2], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed value in '%s'", s.Text())
+			}
+			values[fields[1]] = intVal
+			continue
+		}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("malformed value in '%s'", s.Text())
+			}
+			intVal, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed value in '%s'", s.Text())
+			}
+			values[kv[0]] = intVal
+		}
+	}
+	return devices, s.Err()
+}
+
+// BlockDeviceName returns the name of the block device with the given
+// "major:minor" number, as found in /sys/dev/block, or the device number
+// itself if the device is not found.
+func BlockDeviceName(majorMinor string) string {
+	target, err := os.Readlink(sysDevBlockPath + "/" + majorMinor)
+	if err != nil {
+		return majorMinor
+	}
+	return filepath.Base(target)
+}
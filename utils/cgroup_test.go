@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"bufio"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseCgroupPaths(t *testing.T) {
+	cgroupStr := `11:cpu,cpuacct:/docker/4f2c
+10:memory:/docker/4f2c
+1:name=systemd:/docker/4f2c
+`
+	paths, err := parseCgroupPaths(bufio.NewScanner(strings.NewReader(cgroupStr)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, controller := range []string{"cpu", "cpuacct", "memory", "systemd"} {
+		if paths[controller] != "/docker/4f2c" {
+			t.Errorf("path of %s:\ngot  %q\nwant %q", controller, paths[controller], "/docker/4f2c")
+		}
+	}
+	if _, err := parseCgroupPaths(bufio.NewScanner(strings.NewReader("cpu\n"))); err == nil {
+		t.Errorf("malformed line should return an error")
+	}
+}
+
+func TestParseKeyValues(t *testing.T) {
+	statStr := `nr_periods 10
+nr_throttled 2
+throttled_time 1234567
+`
+	stat, err := parseKeyValues(bufio.NewScanner(strings.NewReader(statStr)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if stat["nr_periods"] != 10 || stat["nr_throttled"] != 2 || stat["throttled_time"] != 1234567 {
+		t.Errorf("unexpected values: %v", stat)
+	}
+}
+
+func TestParseDeviceValues(t *testing.T) {
+	serviceBytesStr := `8:0 Read 4096
+8:0 Write 8192
+8:0 Total 12288
+Total 12288
+`
+	devices, err := parseDeviceValues(bufio.NewScanner(strings.NewReader(serviceBytesStr)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if devices["8:0"]["Read"] != 4096 || devices["8:0"]["Write"] != 8192 || len(devices) != 1 {
+		t.Errorf("unexpected values: %v", devices)
+	}
+
+	ioStatStr := `8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0
+253:1 rbytes=1 wbytes=2 rios=3 wios=4 dbytes=0 dios=0
+`
+	devices, err = parseDeviceValues(bufio.NewScanner(strings.NewReader(ioStatStr)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if devices["8:0"]["wbytes"] != 8192 || devices["253:1"]["wios"] != 4 || len(devices) != 2 {
+		t.Errorf("unexpected values: %v", devices)
+	}
+}
+
+func TestDetectCgroupMode(t *testing.T) {
+	tests := []struct {
+		files []string
+		want  CgroupMode
+	}{
+		{[]string{"cpu/tasks", "memory/tasks"}, CgroupV1},
+		{[]string{"cpu/tasks", "memory/tasks", "unified/cgroup.controllers"}, CgroupHybrid},
+		{[]string{"cgroup.controllers"}, CgroupV2},
+	}
+	for _, tt := range tests {
+		root := t.TempDir()
+		for _, file := range tt.files {
+			path := filepath.Join(root, file)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		t.Setenv("CGROUP_ROOT", root+"/")
+		if got := DetectCgroupMode(); got != tt.want {
+			t.Errorf("mode of %v:\ngot  %s\nwant %s", tt.files, got, tt.want)
+		}
+		if got, want := UnifiedCgroupDir(tt.want, "/docker/abc"), root+map[CgroupMode]string{
+			CgroupV1:     "/docker/abc",
+			CgroupHybrid: "/unified/docker/abc",
+			CgroupV2:     "/docker/abc",
+		}[tt.want]; got != want {
+			t.Errorf("unified directory in %s:\ngot  %s\nwant %s", tt.want, got, want)
+		}
+	}
+	if got, want := CgroupDir("memory", "/"), CgroupRoot()+"/memory"; got != want {
+		t.Errorf("root cgroup directory:\ngot  %s\nwant %s", got, want)
+	}
+}
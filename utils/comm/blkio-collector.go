@@ -0,0 +1,97 @@
+package comm
+
+import (
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+const (
+	// BlkioFamily is the metric family name of cgroup block I/O statistics.
+	BlkioFamily = "blkio"
+	// DeviceLabel is the label key of the block device name of a sample.
+	DeviceLabel = "device"
+	// DeviceNumberLabel is the label key of the "major:minor" number of the
+	// block device of a sample.
+	DeviceNumberLabel = "device_number"
+)
+
+// blkioFiles maps the entries of the cgroup v1 blkio files to metric names.
+var blkioFiles = []struct {
+	file  string
+	names map[string]string
+}{
+	{"blkio.throttle.io_service_bytes", map[string]string{"Read": "read_bytes", "Write": "write_bytes"}},
+	{"blkio.throttle.io_serviced", map[string]string{"Read": "read_ops", "Write": "write_ops"}},
+}
+
+// ioStatNames maps the entries of the cgroup v2 io.stat file to metric names.
+var ioStatNames = map[string]string{
+	"rbytes": "read_bytes",
+	"wbytes": "write_bytes",
+	"rios":   "read_ops",
+	"wios":   "write_ops",
+}
+
+// BlkioCollector collects the block I/O statistics of containers per device.
+type BlkioCollector struct {
+	mode u.CgroupMode
+}
+
+// NewBlkioCollector returns a BlkioCollector of the given layout of the cgroup
+// hierarchies.
+func NewBlkioCollector(mode u.CgroupMode) *BlkioCollector {
+	return &BlkioCollector{mode: mode}
+}
+
+func (bc *BlkioCollector) Name() string {
+	return BlkioFamily
+}
+
+func (bc *BlkioCollector) Discover(cont *Container) error {
+	return cont.UpdateCgroupPaths()
+}
+
+// Collect returns the bytes and the number of operations read and written by
+// the container on each block device since the last collection.
+func (bc *BlkioCollector) Collect(cont *Container) ([]Sample, error) {
+	if bc.mode == u.CgroupV2 {
+		dir := cont.UnifiedCgroupDir(bc.mode)
+		if dir == "" {
+			return nil, errNoCgroup
+		}
+		devices, err := u.ReadCgroupDeviceValues(dir, "io.stat")
+		if err != nil {
+			return nil, err
+		}
+		return bc.samples(cont, devices, ioStatNames), nil
+	}
+	dir := cont.CgroupDir(bc.mode, "blkio")
+	if dir == "" {
+		return nil, errNoCgroup
+	}
+	var samples []Sample
+	for _, bf := range blkioFiles {
+		devices, err := u.ReadCgroupDeviceValues(dir, bf.file)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, bc.samples(cont, devices, bf.names)...)
+	}
+	return samples, nil
+}
+
+func (bc *BlkioCollector) samples(cont *Container, devices map[string]map[string]int64, names map[string]string) []Sample {
+	f := cont.Family(BlkioFamily)
+	var samples []Sample
+	for device, values := range devices {
+		labels := map[string]string{
+			DeviceLabel:       u.BlockDeviceName(device),
+			DeviceNumberLabel: device,
+		}
+		for key, name := range names {
+			if value, ok := values[key]; ok {
+				samples = append(samples, f.Update(cont, name, labels, value))
+			}
+		}
+	}
+	return samples
+}
@@ -0,0 +1,106 @@
+package comm
+
+import (
+	"errors"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+var errNoCgroup = errors.New("cgroup of container not found")
+
+// cgroupFile is a single value cgroup file reported as the metric name.
+type cgroupFile struct {
+	file string
+	name string
+	kind MetricKind
+}
+
+// cgroupKey is an entry of a flat keyed cgroup file reported as the metric
+// name. Values are multiplied by scale, if set, so they have the same unit in
+// cgroup v1 and v2.
+type cgroupKey struct {
+	file  string
+	key   string
+	name  string
+	kind  MetricKind
+	scale int64
+}
+
+// UpdateCgroupPaths reads the cgroup path of each controller of the container
+// from /proc/<pid>/cgroup.
+func (cont *Container) UpdateCgroupPaths() error {
+	log.Debug("")
+	paths, err := u.ReadCgroupPaths(cont.PID)
+	if err != nil {
+		return err
+	}
+	cont.CgroupPaths = paths
+	return nil
+}
+
+// CgroupDir returns the cgroup directory of the container in the hierarchy of
+// the given controller, or an empty string if it is not known, for the given
+// layout of the cgroup hierarchies. On cgroup v2 all controllers share the same
+// directory of the unified hierarchy.
+func (cont *Container) CgroupDir(mode u.CgroupMode, controller string) string {
+	if mode == u.CgroupV2 {
+		return cont.UnifiedCgroupDir(mode)
+	}
+	path, ok := cont.CgroupPaths[controller]
+	if !ok {
+		return ""
+	}
+	return u.CgroupDir(controller, path)
+}
+
+// UnifiedCgroupDir returns the cgroup directory of the container in the
+// unified hierarchy, or an empty string if it is not known or if there is no
+// unified hierarchy.
+func (cont *Container) UnifiedCgroupDir(mode u.CgroupMode) string {
+	path, ok := cont.CgroupPaths[""]
+	if !ok || mode == u.CgroupV1 {
+		return ""
+	}
+	return u.UnifiedCgroupDir(mode, path)
+}
+
+// storeCgroupFiles reads the given single value files from the cgroup
+// directory dir and stores them in the metric family f of the container.
+func storeCgroupFiles(f *MetricFamily, cont *Container, dir string, files []cgroupFile) []Sample {
+	var samples []Sample
+	for _, cf := range files {
+		value, err := u.ReadCgroupInt64(dir, cf.file)
+		if err != nil {
+			log.Debug("Error while reading %s of %s: %s", cf.file, cont.DockerID, err)
+			continue
+		}
+		samples = append(samples, f.Store(cont, cf.name, cf.kind, nil, value))
+	}
+	return samples
+}
+
+// storeCgroupKeys reads the given entries of flat keyed files from the cgroup
+// directory dir and stores them in the metric family f of the container.
+func storeCgroupKeys(f *MetricFamily, cont *Container, dir string, keys []cgroupKey) []Sample {
+	var samples []Sample
+	files := map[string]map[string]int64{}
+	for _, ck := range keys {
+		values, ok := files[ck.file]
+		if !ok {
+			var err error
+			if values, err = u.ReadCgroupKeyValues(dir, ck.file); err != nil {
+				log.Debug("Error while reading %s of %s: %s", ck.file, cont.DockerID, err)
+			}
+			files[ck.file] = values
+		}
+		value, ok := values[ck.key]
+		if !ok {
+			continue
+		}
+		if ck.scale != 0 {
+			value *= ck.scale
+		}
+		samples = append(samples, f.Store(cont, ck.name, ck.kind, nil, value))
+	}
+	return samples
+}
@@ -0,0 +1,255 @@
+package comm
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+// cgroupTree creates the given files in a temporary cgroup root.
+func cgroupTree(t *testing.T, files map[string]string) {
+	root := t.TempDir()
+	for file, content := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("CGROUP_ROOT", root)
+}
+
+// collect returns the samples of the given collector keyed by metric name and
+// labels, checking their kind.
+func collect(t *testing.T, c Collector, cont *Container, counters ...string) map[string]Sample {
+	samples, err := c.Collect(cont)
+	if err != nil {
+		t.Fatalf("error while collecting %s metrics: %s", c.Name(), err)
+	}
+	isCounter := map[string]bool{}
+	for _, name := range counters {
+		isCounter[name] = true
+	}
+	byKey := map[string]Sample{}
+	for _, s := range samples {
+		key := metricKey(s.Name, s.Labels)
+		byKey[key] = s
+		if want := map[bool]MetricKind{true: Counter, false: Gauge}[isCounter[s.Name]]; s.Kind != want {
+			t.Errorf("kind of %s %s:\ngot  %s\nwant %s", c.Name(), key, s.Kind, want)
+		}
+	}
+	return byKey
+}
+
+// checkValues checks the values read of the given samples.
+func checkValues(t *testing.T, family string, samples map[string]Sample, want map[string]int64) {
+	if len(samples) != len(want) {
+		t.Errorf("number of %s samples:\ngot  %d\nwant %d", family, len(samples), len(want))
+	}
+	for key, value := range want {
+		s, ok := samples[key]
+		if !ok {
+			t.Errorf("%s %s: missing", family, key)
+			continue
+		}
+		if s.ValueRead != value {
+			t.Errorf("%s %s:\ngot  %d\nwant %d", family, key, s.ValueRead, value)
+		}
+	}
+}
+
+var cgroupV1Files = map[string]string{
+	"cpuacct/docker/abc/cpuacct.usage":                 "1000\n",
+	"cpuacct/docker/abc/cpuacct.usage_percpu":          "600 400 \n",
+	"cpu/docker/abc/cpu.stat":                          "nr_periods 10\nnr_throttled 2\nthrottled_time 300\n",
+	"memory/docker/abc/memory.usage_in_bytes":          "4096\n",
+	"memory/docker/abc/memory.max_usage_in_bytes":      "8192\n",
+	"memory/docker/abc/memory.limit_in_bytes":          "9223372036854771712\n",
+	"memory/docker/abc/memory.failcnt":                 "3\n",
+	"memory/docker/abc/memory.stat":                    "cache 100\nrss 200\nswap 0\npgfault 5\npgmajfault 1\ntotal_rss 200\n",
+	"memory/docker/abc/memory.oom_control":             "oom_kill_disable 0\nunder_oom 0\noom_kill 2\n",
+	"blkio/docker/abc/blkio.throttle.io_service_bytes": "8:0 Read 4096\n8:0 Write 8192\n8:0 Total 12288\nTotal 12288\n",
+	"blkio/docker/abc/blkio.throttle.io_serviced":      "8:0 Read 1\n8:0 Write 2\n8:0 Total 3\nTotal 3\n",
+	"pids/docker/abc/pids.current":                     "7\n",
+	"pids/docker/abc/pids.max":                         "max\n",
+}
+
+var cgroupV1Paths = map[string]string{
+	"cpu":     "/docker/abc",
+	"cpuacct": "/docker/abc",
+	"memory":  "/docker/abc",
+	"blkio":   "/docker/abc",
+	"pids":    "/docker/abc",
+}
+
+var cgroupV2Files = map[string]string{
+	"cgroup.controllers":                         "cpu io memory pids\n",
+	"system.slice/abc.scope/cpu.stat":            "usage_usec 5\nuser_usec 3\nsystem_usec 2\nnr_periods 10\nnr_throttled 2\nthrottled_usec 3\n",
+	"system.slice/abc.scope/memory.current":      "4096\n",
+	"system.slice/abc.scope/memory.peak":         "8192\n",
+	"system.slice/abc.scope/memory.max":          "max\n",
+	"system.slice/abc.scope/memory.swap.current": "0\n",
+	"system.slice/abc.scope/memory.stat":         "anon 200\nfile 100\npgfault 5\npgmajfault 1\n",
+	"system.slice/abc.scope/memory.events":       "low 0\nhigh 0\nmax 3\noom 2\noom_kill 2\n",
+	"system.slice/abc.scope/io.stat":             "8:0 rbytes=4096 wbytes=8192 rios=1 wios=2 dbytes=0 dios=0\n",
+	"system.slice/abc.scope/pids.current":        "7\n",
+	"system.slice/abc.scope/pids.max":            "100\n",
+}
+
+func TestCgroupCollectorsV1(t *testing.T) {
+	cgroupTree(t, cgroupV1Files)
+	cont := &Container{DockerID: "abc", CgroupPaths: cgroupV1Paths}
+	mode := u.CgroupV1
+	checkValues(t, CPUFamily, collect(t, NewCPUCollector(mode), cont, "usage", "usage_percpu", "nr_periods", "nr_throttled", "throttled_time"), map[string]int64{
+		"usage":              1000,
+		"usage_percpu,cpu=0": 600,
+		"usage_percpu,cpu=1": 400,
+		"nr_periods":         10,
+		"nr_throttled":       2,
+		"throttled_time":     300,
+	})
+	checkValues(t, MemoryFamily, collect(t, NewMemoryCollector(mode), cont, "failcnt", "pgfault", "pgmajfault", "oom_kill"), map[string]int64{
+		"usage":      4096,
+		"max_usage":  8192,
+		"limit":      9223372036854771712,
+		"failcnt":    3,
+		"rss":        200,
+		"cache":      100,
+		"swap":       0,
+		"pgfault":    5,
+		"pgmajfault": 1,
+		"oom_kill":   2,
+	})
+	blkio := collect(t, NewBlkioCollector(mode), cont, "read_bytes", "write_bytes", "read_ops", "write_ops")
+	device := "device=" + u.BlockDeviceName("8:0") + ",device_number=8:0"
+	checkValues(t, BlkioFamily, blkio, map[string]int64{
+		"read_bytes," + device:  4096,
+		"write_bytes," + device: 8192,
+		"read_ops," + device:    1,
+		"write_ops," + device:   2,
+	})
+	checkValues(t, PidsFamily, collect(t, NewPidsCollector(mode), cont), map[string]int64{
+		"current": 7,
+		"limit":   math.MaxInt64,
+	})
+	if dir := cont.UnifiedCgroupDir(mode); dir != "" {
+		t.Errorf("unified directory in v1:\ngot  %s\nwant none", dir)
+	}
+}
+
+func TestCgroupCollectorsV2(t *testing.T) {
+	cgroupTree(t, cgroupV2Files)
+	if mode := u.DetectCgroupMode(); mode != u.CgroupV2 {
+		t.Fatalf("invalid mode:\ngot  %s\nwant %s", mode, u.CgroupV2)
+	}
+	cont := &Container{DockerID: "abc", CgroupPaths: map[string]string{"": "/system.slice/abc.scope"}}
+	mode := u.CgroupV2
+	// Microseconds are converted to nanoseconds, as in cgroup v1.
+	checkValues(t, CPUFamily, collect(t, NewCPUCollector(mode), cont, "usage", "nr_periods", "nr_throttled", "throttled_time"), map[string]int64{
+		"usage":          5000,
+		"nr_periods":     10,
+		"nr_throttled":   2,
+		"throttled_time": 3000,
+	})
+	checkValues(t, MemoryFamily, collect(t, NewMemoryCollector(mode), cont, "failcnt", "pgfault", "pgmajfault", "oom_kill"), map[string]int64{
+		"usage":      4096,
+		"max_usage":  8192,
+		"limit":      math.MaxInt64,
+		"swap":       0,
+		"rss":        200,
+		"cache":      100,
+		"pgfault":    5,
+		"pgmajfault": 1,
+		"failcnt":    3,
+		"oom_kill":   2,
+	})
+	device := "device=" + u.BlockDeviceName("8:0") + ",device_number=8:0"
+	checkValues(t, BlkioFamily, collect(t, NewBlkioCollector(mode), cont, "read_bytes", "write_bytes", "read_ops", "write_ops"), map[string]int64{
+		"read_bytes," + device:  4096,
+		"write_bytes," + device: 8192,
+		"read_ops," + device:    1,
+		"write_ops," + device:   2,
+	})
+	checkValues(t, PidsFamily, collect(t, NewPidsCollector(mode), cont), map[string]int64{
+		"current": 7,
+		"limit":   100,
+	})
+}
+
+func TestCgroupCollectorsHybrid(t *testing.T) {
+	files := map[string]string{
+		"unified/cgroup.controllers":    "",
+		"unified/docker/abc/cpu.stat":   "usage_usec 5\n",
+		"unified/docker/abc/pids.max":   "1\n",
+		"unified/docker/abc/memory.max": "1\n",
+	}
+	for file, content := range cgroupV1Files {
+		files[file] = content
+	}
+	cgroupTree(t, files)
+	if mode := u.DetectCgroupMode(); mode != u.CgroupHybrid {
+		t.Fatalf("invalid mode:\ngot  %s\nwant %s", mode, u.CgroupHybrid)
+	}
+	paths := map[string]string{"": "/docker/abc"}
+	for controller, path := range cgroupV1Paths {
+		paths[controller] = path
+	}
+	cont := &Container{DockerID: "abc", CgroupPaths: paths}
+	mode := u.CgroupHybrid
+	// Controllers are only in the v1 hierarchies.
+	cpu := collect(t, NewCPUCollector(mode), cont, "usage", "usage_percpu", "nr_periods", "nr_throttled", "throttled_time")
+	if s := cpu["usage"]; s.ValueRead != 1000 {
+		t.Errorf("cpu usage in hybrid:\ngot  %d\nwant %d", s.ValueRead, 1000)
+	}
+	if s := collect(t, NewMemoryCollector(mode), cont, "failcnt", "pgfault", "pgmajfault", "oom_kill")["limit"]; s.ValueRead != 9223372036854771712 {
+		t.Errorf("memory limit in hybrid:\ngot  %d\nwant %d", s.ValueRead, int64(9223372036854771712))
+	}
+	if s := collect(t, NewPidsCollector(mode), cont)["limit"]; s.ValueRead != math.MaxInt64 {
+		t.Errorf("pids limit in hybrid:\ngot  %d\nwant %d", s.ValueRead, int64(math.MaxInt64))
+	}
+	if got, want := cont.UnifiedCgroupDir(mode), u.CgroupRoot()+"/unified/docker/abc"; got != want {
+		t.Errorf("unified directory in hybrid:\ngot  %s\nwant %s", got, want)
+	}
+}
+
+func TestCgroupCollectorsNoCgroup(t *testing.T) {
+	cgroupTree(t, cgroupV1Files)
+	cont := &Container{DockerID: "abc"}
+	for _, c := range []Collector{NewCPUCollector(u.CgroupV1), NewMemoryCollector(u.CgroupV2), NewBlkioCollector(u.CgroupV2), NewPidsCollector(u.CgroupV1)} {
+		if _, err := c.Collect(cont); err != errNoCgroup {
+			t.Errorf("%s of a container without cgroup:\ngot  %v\nwant %s", c.Name(), err, errNoCgroup)
+		}
+	}
+}
+
+func TestPressureCollector(t *testing.T) {
+	pressure := "some avg10=1.50 avg60=0.25 avg300=0.00 total=1234\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=56\n"
+	cgroupTree(t, map[string]string{
+		"unified/cgroup.controllers":      "",
+		"unified/docker/abc/cpu.pressure": pressure,
+	})
+	cont := &Container{DockerID: "abc", CgroupPaths: map[string]string{"": "/docker/abc", "cpu": "/docker/abc"}}
+
+	// cgroup v1 has no pressure, which isn't an error.
+	if samples, err := NewPressureCollector(u.CgroupV1).Collect(cont); err != nil || len(samples) != 0 {
+		t.Errorf("pressure in v1:\ngot  %v, %v\nwant no samples and no error", samples, err)
+	}
+
+	samples := collect(t, NewPressureCollector(u.CgroupHybrid), cont, "total")
+	checkValues(t, PressureFamily, samples, map[string]int64{
+		"avg10,resource=cpu,scope=some":  150,
+		"avg60,resource=cpu,scope=some":  25,
+		"avg300,resource=cpu,scope=some": 0,
+		"total,resource=cpu,scope=some":  1234,
+		"avg10,resource=cpu,scope=full":  0,
+		"avg60,resource=cpu,scope=full":  0,
+		"avg300,resource=cpu,scope=full": 0,
+		"total,resource=cpu,scope=full":  56,
+	})
+}
@@ -0,0 +1,95 @@
+package comm
+
+import (
+	"fmt"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+const (
+	// InterfaceLabel is the label key of the network interface of a sample.
+	InterfaceLabel = "interface"
+	// Collectors are the names of all available collectors.
+	Collectors = NetworkFamily + "|" + CPUFamily + "|" + MemoryFamily + "|" + BlkioFamily + "|" + PidsFamily + "|" + PressureFamily + "|" + SNMPFamily + "|" + SocketsFamily + "|" + ProcessesFamily + "|" + SystemFamily
+	// DefaultCollectors are the collectors used when none are specified.
+	DefaultCollectors = NetworkFamily + "," + CPUFamily + "," + MemoryFamily + "," + BlkioFamily + "," + PidsFamily + "," + PressureFamily + "," + SNMPFamily + "," + SocketsFamily + "," + ProcessesFamily + "," + SystemFamily
+)
+
+// Collector reads a family of metrics from the containers of a node.
+type Collector interface {
+	// Name returns the name of the metric family read by the collector.
+	Name() string
+	// Discover finds which metrics are available for the given container.
+	// It is called when a container is created or activated.
+	Discover(cont *Container) error
+	// Collect reads the metrics of the given container and returns them
+	// as samples. It is called on every tick for active containers.
+	Collect(cont *Container) ([]Sample, error)
+}
+
+// NodeCollector is implemented by collectors that also read metrics of the
+// node itself.
+type NodeCollector interface {
+	// CollectNode reads the metrics of the node and returns them as samples
+	// without container. It is called on every tick.
+	CollectNode(node *Node) ([]Sample, error)
+}
+
+// netNSCollector is implemented by collectors whose metrics belong to the
+// network namespace of containers rather than to the containers themselves.
+type netNSCollector interface {
+	netNSScoped()
+}
+
+// CollectsPerNetNS returns true if the metrics of the given collector belong
+// to network namespaces, in which case they are only collected from the
+// owner of each network namespace and not from host network containers.
+func CollectsPerNetNS(c Collector) bool {
+	_, ok := c.(netNSCollector)
+	return ok
+}
+
+// Sample is a single metric value of a container, or of the node if Container
+// is nil, ready to be stored. The Value of counters is the difference since
+// the previous sample while the Value of gauges is the absolute value read.
+// ValueRead is the absolute value read for both.
+type Sample struct {
+	Family    string
+	Name      string
+	Kind      MetricKind
+	Labels    map[string]string
+	Value     int64
+	ValueRead int64
+	Container *Container
+	// Interface is the network interface of network samples, nil otherwise.
+	Interface *NetworkInterface
+}
+
+// NewCollectorOf returns the collector with the given name. Collectors of
+// cgroup statistics read them from the given layout of the cgroup hierarchies.
+func NewCollectorOf(name string, mode u.CgroupMode) (Collector, error) {
+	switch name {
+	case NetworkFamily:
+		return NewNetworkCollector(), nil
+	case CPUFamily:
+		return NewCPUCollector(mode), nil
+	case MemoryFamily:
+		return NewMemoryCollector(mode), nil
+	case BlkioFamily:
+		return NewBlkioCollector(mode), nil
+	case PidsFamily:
+		return NewPidsCollector(mode), nil
+	case PressureFamily:
+		return NewPressureCollector(mode), nil
+	case SNMPFamily:
+		return NewSNMPCollector(), nil
+	case SocketsFamily:
+		return NewSocketsCollector(), nil
+	case ProcessesFamily:
+		return NewProcessesCollector(mode), nil
+	case SystemFamily:
+		return NewSystemCollector(), nil
+	default:
+		return nil, fmt.Errorf("invalid collector '%s'", name)
+	}
+}
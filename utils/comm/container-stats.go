@@ -1,13 +1,12 @@
 package comm
 
 import (
-	"errors"
+	"fmt"
 	"os"
 	"strconv"
-	"strings"
 	"time"
 
-	ue "github.com/cilium-team/docker-collector/utils/executable"
+	u "github.com/cilium-team/docker-collector/utils"
 )
 
 // Statistics based on: https://www.kernel.org/doc/Documentation/ABI/testing/sysfs-class-net-statistics
@@ -43,43 +42,83 @@ var NetStatsNames = []string{
 
 type Node struct {
 	//	NumberOfActCont int
-	Name         string
-	DockerClient Docker `json:"-",sql:"-"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
-	DeletedAt    *time.Time
-	Containers   []Container
+	Name           string
+	Runtime        Runtime `json:"-" sql:"-"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      *time.Time
+	Containers     []Container
+	MetricFamilies map[string]*MetricFamily `json:"-" sql:"-"`
+	// NetworkInterfaces are the interfaces of the host network namespace.
+	NetworkInterfaces []NetworkInterface `json:"-" sql:"-"`
+	// HostLinks are the interfaces of the host network namespace read on
+	// the last UpdateHostLinks.
+	HostLinks []u.NetLink `json:"-" sql:"-"`
 }
 
 type Container struct {
 	ID                int `json:"-"`
-	PID               int `json:"-",sql:"-"`
+	PID               int `json:"-" sql:"-"`
 	DockerID          string
 	Name              string
 	NodeName          string
+	Metadata          ContainerMetadata
+	DockerLabels      map[string]string `json:"-" sql:"-"`
+	NetNSPath         string            `json:"-" sql:"-"`
+	NetNS             string            `json:"-" sql:"-"`
+	NetNSGroup        string
+	NetNSOwner        bool `json:"-" sql:"-"`
+	HostNetwork       bool
 	NetworkInterfaces []NetworkInterface
-	IsActive          bool `sql:"-"`
+	MetricFamilies    map[string]*MetricFamily `json:"-" sql:"-"`
+	CgroupPaths       map[string]string        `json:"-" sql:"-"`
+	IsActive          bool                     `sql:"-"`
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 	DeletedAt         *time.Time
+	// hostLinks are the interfaces of the host network namespace shared by
+	// the node on UpdateHostLinks.
+	hostLinks []u.NetLink
 }
 
 type NetworkInterface struct {
-	ID           int `json:"-"`
-	ContainerID  int `json:"-",sql:"index"`
-	Name         string
+	ID          int `json:"-"`
+	ContainerID int `json:"-" sql:"index"`
+	Name        string
+	// Type is the type of virtual interface, such as veth, macvlan, ipvlan
+	// or vxlan, empty for physical interfaces.
+	Type       string
+	OperState  string
+	Carrier    bool
+	MTU        int
+	MACAddress string
+	// Speed is the link speed in Mbit/s, 0 if unknown.
+	Speed int64
+	// Addresses are the IPv4 and IPv6 addresses in CIDR notation.
+	Addresses []string
+	// HostInterface is the host-side peer of a veth, or the host interface
+	// a macvlan or ipvlan is created on.
+	HostInterface string
+	// Bridge is the bridge, or OVS datapath, HostInterface is attached to
+	// and BridgeType its type, such as bridge or openvswitch.
+	Bridge       string
+	BridgeType   string
+	Index        int  `json:"-" sql:"-"`
+	ParentIndex  int  `json:"-" sql:"-"`
 	IsActive     bool `json:"-"`
 	NetworkStats []NetworkStat
 }
 
 type NetworkStat struct {
 	ID                 int `json:"-"`
-	NetworkInterfaceID int `json:"-",sql:"index"`
+	NetworkInterfaceID int `json:"-" sql:"index"`
 	Name               string
-	ExecID             string `json:"-",sql:"-"`
-	ValueRead          int64  `json:"-",sql:"-"`
-	LastValueRead      int64  `json:"-",sql:"-"`
+	ExecID             string `json:"-" sql:"-"`
+	ValueRead          int64  `json:"-" sql:"-"`
+	LastValueRead      int64  `json:"-" sql:"-"`
 	CurrentValue       int64
+	// read is false until the statistic is read for the first time.
+	read bool
 }
 
 func (n *Node) GetSliceIndex(dockerID string) int {
@@ -95,15 +134,13 @@ func (n *Node) GetSliceIndex(dockerID string) int {
 func (n *Node) Activate(dockerID, dockerPID string) {
 	log.Debug("")
 	if i := n.GetSliceIndex(dockerID); i != -1 {
-		if netInter, err := createNetworkInterfaces(dockerPID); err == nil {
-			n.Containers[i].IsActive = true
-			n.Containers[i].AddNewInterfaces(netInter)
-			if num, err := strconv.Atoi(dockerPID); err != nil {
-				n.Containers[i].PID = num
-			} else {
-				n.Containers[i].PID = 0
-			}
+		n.Containers[i].IsActive = true
+		if num, err := strconv.Atoi(dockerPID); err == nil {
+			n.Containers[i].PID = num
+		} else {
+			n.Containers[i].PID = 0
 		}
+		n.Containers[i].UpdateNetNS()
 	} else {
 		n.Create(dockerID)
 	}
@@ -130,17 +167,41 @@ func (n *Node) ActiveContainers() int {
 	return count
 }
 
-func createNetworkInterfaces(dockerPID string) ([]NetworkInterface, error) {
-	log.Debug("")
-	netInterNames, err := listLocalNetInt(dockerPID)
-	if err != nil {
-		return nil, err
-	}
+// setMetadata sets the attributes of the network interface from the given
+// link.
+func (netInt *NetworkInterface) setMetadata(link u.NetLink) {
+	netInt.Type = link.Kind
+	netInt.OperState = link.OperState
+	netInt.Carrier = link.Carrier
+	netInt.MTU = link.MTU
+	netInt.MACAddress = link.MACAddress
+	netInt.Speed = link.Speed
+	netInt.Addresses = link.Addresses
+	netInt.Index = link.Index
+	netInt.ParentIndex = link.ParentIndex
+}
+
+// copyMetadata copies the attributes of the network interface from the given
+// interface, which is the same interface rediscovered.
+func (netInt *NetworkInterface) copyMetadata(from *NetworkInterface) {
+	netInt.Type = from.Type
+	netInt.OperState = from.OperState
+	netInt.Carrier = from.Carrier
+	netInt.MTU = from.MTU
+	netInt.MACAddress = from.MACAddress
+	netInt.Speed = from.Speed
+	netInt.Addresses = from.Addresses
+	netInt.Index = from.Index
+	netInt.ParentIndex = from.ParentIndex
+}
+
+func newNetworkInterfaces(links []u.NetLink) []NetworkInterface {
 	var networkInterfaces []NetworkInterface
-	for _, netInterName := range netInterNames {
+	for _, link := range links {
 		netInt := NetworkInterface{
-			Name: netInterName,
+			Name: link.Name,
 		}
+		netInt.setMetadata(link)
 		for _, netStatName := range NetStatsNames {
 			networkStat := NetworkStat{
 				Name: netStatName,
@@ -149,38 +210,127 @@ func createNetworkInterfaces(dockerPID string) ([]NetworkInterface, error) {
 		}
 		networkInterfaces = append(networkInterfaces, netInt)
 	}
-	return networkInterfaces, nil
+	return networkInterfaces
 }
 
-func listLocalNetInt(pid string) ([]string, error) {
-	log.Debug("")
-	stdout, err := ue.ExecShCommand(`ls /proc/` + pid + `/root` + netStatsBasePath)
+// readNetLinks returns the network interfaces of the network namespace of the
+// container, which is the one of its process or, for network namespaces
+// without process, the one of NetNSPath.
+func (cont *Container) readNetLinks() ([]u.NetLink, error) {
+	if cont.PID == 0 && cont.NetNSPath != "" {
+		return u.ReadNetLinksPath(cont.NetNSPath)
+	}
+	return readNetLinks(cont.PID)
+}
+
+// readNetLinks returns the network interfaces, with their attributes and
+// statistics, of the network namespace of the process with the given pid.
+// Netlink is used by entering the network namespace of the process, if that is
+// not possible the attributes and statistics are read from sysfs inside the
+// root filesystem of the process.
+func readNetLinks(pid int) ([]u.NetLink, error) {
+	links, err := u.ReadNetLinks(pid)
+	if err == nil {
+		return links, nil
+	}
+	log.Debug("Falling back to sysfs for pid %d: %s", pid, err)
+	netInterNames, err := listLocalNetInt(strconv.Itoa(pid))
 	if err != nil {
-		return nil, err
+		// The root filesystem of containers of other users isn't
+		// accessible when not running privileged, /proc/<pid>/net/dev
+		// might still be.
+		log.Debug("Falling back to net/dev for pid %d: %s", pid, err)
+		return readNetDevLinks(pid)
 	}
-	stdoutstr := string(stdout)
-	var interfaces []string
-	if len(stdoutstr) != 0 {
-		if strings.Contains(stdoutstr, "executable file not found") {
-			log.Debug("stdout %s", stdout)
-			return nil, errors.New("Doesn't support network statistics")
-		}
-		for _, inter := range strings.Split(stdoutstr, "\n") {
-			if len(inter) != 0 {
-				interfaces = append(interfaces, inter)
+	var netDev map[string]map[string]int64
+	for _, netInterName := range netInterNames {
+		intpath := BuildNetworkIntPath(netInterName, "")
+		values, err := u.ReadStatsFromProc(pid, intpath, NetStatsNames)
+		if err != nil {
+			log.Debug("Falling back to net/dev for pid %d: %s", pid, err)
+			if netDev == nil {
+				if netDev, err = u.ReadNetDev(pid); err != nil {
+					log.Error("Error while reading statistics of pid %d: %s", pid, err)
+					netDev = map[string]map[string]int64{}
+				}
 			}
+			values = netDev[netInterName]
 		}
+		link := u.NetLink{Name: netInterName, Stats: values}
+		readSysfsNetLink(pid, &link)
+		links = append(links, link)
 	}
-	return interfaces, nil
+	return links, nil
 }
 
-func (n *Node) Create(dockerID string) error {
+// readNetDevLinks returns the network interfaces, with their statistics only,
+// of the network namespace of the process with the given pid from
+// /proc/<pid>/net/dev.
+func readNetDevLinks(pid int) ([]u.NetLink, error) {
+	netDev, err := u.ReadNetDev(pid)
+	if err != nil {
+		return nil, err
+	}
+	var links []u.NetLink
+	for name, values := range netDev {
+		links = append(links, u.NetLink{Name: name, Stats: values})
+	}
+	return links, nil
+}
+
+// readSysfsNetLink reads the attributes of the given link from sysfs inside
+// the root filesystem of the process with the given pid.
+func readSysfsNetLink(pid int, link *u.NetLink) {
+	netIntPath := netStatsBasePath + link.Name + "/"
+	if v, err := u.ReadInt64FromProc(pid, netIntPath+"ifindex"); err == nil {
+		link.Index = int(v)
+	}
+	if v, err := u.ReadInt64FromProc(pid, netIntPath+"iflink"); err == nil {
+		link.ParentIndex = int(v)
+	}
+	if v, err := u.ReadInt64FromProc(pid, netIntPath+"master/ifindex"); err == nil {
+		link.MasterIndex = int(v)
+	}
+	if v, err := u.ReadInt64FromProc(pid, netIntPath+"mtu"); err == nil {
+		link.MTU = int(v)
+	}
+	if v, err := u.ReadInt64FromProc(pid, netIntPath+"carrier"); err == nil {
+		link.Carrier = v == 1
+	}
+	if v, err := u.ReadContainerFromProc(pid, netIntPath+"operstate"); err == nil {
+		link.OperState = v
+	}
+	if v, err := u.ReadContainerFromProc(pid, netIntPath+"address"); err == nil {
+		link.MACAddress = v
+	}
+	link.Speed = readNetIntSpeed(pid, link.Name)
+}
+
+// readNetIntSpeed returns the speed, in Mbit/s, of the given network interface
+// or 0 if the interface doesn't report it. It is only used when the network
+// namespace can't be entered, so it is best-effort: the sysfs of the root
+// filesystem of the process might not be the one of its network namespace and
+// isn't readable for rootless containers.
+func readNetIntSpeed(pid int, netIntName string) int64 {
+	speed, err := u.ReadInt64FromProc(pid, netStatsBasePath+netIntName+"/speed")
+	if err != nil || speed < 0 {
+		return 0
+	}
+	return speed
+}
+
+func listLocalNetInt(pid string) ([]string, error) {
 	log.Debug("")
-	inspectCont, err := n.DockerClient.InspectContainer(dockerID)
+	num, err := strconv.Atoi(pid)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	networkInterfaces, err := createNetworkInterfaces(strconv.Itoa(inspectCont.State.Pid))
+	return u.ListContainerDir(num, netStatsBasePath)
+}
+
+func (n *Node) Create(dockerID string) error {
+	log.Debug("")
+	rc, err := n.Runtime.InspectContainer(dockerID)
 	if err != nil {
 		return err
 	}
@@ -189,21 +339,34 @@ func (n *Node) Create(dockerID string) error {
 		log.Error("Error while getting the host name: %v", err)
 	}
 	container := Container{
-		NetworkInterfaces: networkInterfaces,
-		IsActive:          true,
-		DockerID:          dockerID,
-		Name:              inspectCont.Name,
-		NodeName:          hn,
-		PID:               inspectCont.State.Pid,
+		IsActive:     rc.Running,
+		DockerID:     dockerID,
+		Name:         rc.Name,
+		NodeName:     hn,
+		PID:          rc.PID,
+		Metadata:     newContainerMetadata(rc),
+		DockerLabels: rc.Labels,
 	}
+	container.UpdateNetNS()
 	n.Containers = append(n.Containers, container)
 	return nil
 }
 
 func (cont *Container) UpdateLastValue() {
 	log.Debug("")
-	for _, netInter := range cont.NetworkInterfaces {
+	updateLastValue(cont.NetworkInterfaces)
+}
+
+// updateLastValue computes the difference since the last read of each
+// statistic of the given interfaces. The first read of a statistic has a
+// difference of 0, as it has no previous read.
+func updateLastValue(netInters []NetworkInterface) {
+	for _, netInter := range netInters {
 		for j, _ := range netInter.NetworkStats {
+			if !netInter.NetworkStats[j].read {
+				netInter.NetworkStats[j].LastValueRead = netInter.NetworkStats[j].ValueRead
+				netInter.NetworkStats[j].read = true
+			}
 			netInter.NetworkStats[j].CurrentValue,
 				netInter.NetworkStats[j].LastValueRead =
 				netInter.NetworkStats[j].ValueRead-netInter.NetworkStats[j].LastValueRead,
@@ -215,31 +378,99 @@ func (cont *Container) UpdateLastValue() {
 
 func (cont *Container) AddNewInterfaces(newNetInterfaces []NetworkInterface) {
 	log.Debug("")
-	for i := range cont.NetworkInterfaces {
-		cont.NetworkInterfaces[i].IsActive = false
+	cont.NetworkInterfaces = addNewInterfaces(cont.Name, cont.NetworkInterfaces, newNetInterfaces)
+}
+
+// addNewInterfaces activates the interfaces of netInters found in
+// newNetInterfaces, deactivates the others and appends the new ones.
+func addNewInterfaces(owner string, netInters, newNetInterfaces []NetworkInterface) []NetworkInterface {
+	for i := range netInters {
+		netInters[i].IsActive = false
 	}
 	for _, newNetInter := range newNetInterfaces {
 		gotActive := false
-		for i := range cont.NetworkInterfaces {
-			if newNetInter.Name == cont.NetworkInterfaces[i].Name {
+		for i := range netInters {
+			if newNetInter.Name == netInters[i].Name {
 				log.Debug("Activating %s", newNetInter.Name)
-				cont.NetworkInterfaces[i].IsActive = true
+				netInters[i].IsActive = true
+				netInters[i].copyMetadata(&newNetInter)
 				gotActive = true
 				break
 			}
 		}
 		if !gotActive {
-			log.Info("Adding interface '%s' to '%s'", newNetInter.Name, cont.Name)
+			log.Info("Adding interface '%s' to '%s'", newNetInter.Name, owner)
 			newNetInter.IsActive = true
-			cont.NetworkInterfaces = append(cont.NetworkInterfaces, newNetInter)
+			netInters = append(netInters, newNetInter)
+		}
+	}
+	return netInters
+}
+
+func (cont *Container) UpdateNetInterfaces() error {
+	log.Debug("")
+	links, err := cont.readNetLinks()
+	if err != nil {
+		return err
+	}
+	cont.AddNewInterfaces(newNetworkInterfaces(links))
+	return nil
+}
+
+// UpdateNetworkStats rediscovers the network interfaces of the container and
+// reads the statistics of all its active interfaces.
+func (cont *Container) UpdateNetworkStats() error {
+	log.Debug("")
+	links, err := cont.readNetLinks()
+	if err != nil {
+		return err
+	}
+	cont.AddNewInterfaces(newNetworkInterfaces(links))
+	setNetworkStats(cont.NetworkInterfaces, links)
+	return nil
+}
+
+// UpdateNetworkStats rediscovers the network interfaces of the host network
+// namespace and reads their statistics from the host interfaces last read by
+// UpdateHostLinks. Interfaces that no longer exist, such as the veth of
+// removed containers, are forgotten.
+func (n *Node) UpdateNetworkStats() error {
+	log.Debug("")
+	links := n.HostLinks
+	if links == nil {
+		return fmt.Errorf("host interfaces of '%s' not read", n.Name)
+	}
+	netInters := addNewInterfaces(n.Name, n.NetworkInterfaces, newNetworkInterfaces(links))
+	n.NetworkInterfaces = n.NetworkInterfaces[:0]
+	for _, netInter := range netInters {
+		if netInter.IsActive {
+			n.NetworkInterfaces = append(n.NetworkInterfaces, netInter)
 		}
 	}
+	setNetworkStats(n.NetworkInterfaces, links)
+	return nil
 }
 
-func (cont *Container) UpdateNetInterfaces() {
+// UpdateLastValue computes the difference since the last read of the
+// statistics of the host network interfaces.
+func (n *Node) UpdateLastValue() {
 	log.Debug("")
-	if inter, err := createNetworkInterfaces(strconv.Itoa(cont.PID)); err == nil {
-		cont.AddNewInterfaces(inter)
+	updateLastValue(n.NetworkInterfaces)
+}
+
+// setNetworkStats sets the statistics read of the active interfaces of
+// netInters from the given links.
+func setNetworkStats(netInters []NetworkInterface, links []u.NetLink) {
+	for _, link := range links {
+		for _, netInter := range netInters {
+			if netInter.Name != link.Name || !netInter.IsActive {
+				continue
+			}
+			for j, netStat := range netInter.NetworkStats {
+				netInter.NetworkStats[j].ValueRead = link.Stats[netStat.Name]
+				log.Debug("%s/%s: %d", netInter.Name, netStat.Name, link.Stats[netStat.Name])
+			}
+		}
 	}
 }
 
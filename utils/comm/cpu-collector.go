@@ -0,0 +1,87 @@
+package comm
+
+import (
+	"strconv"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+const (
+	// CPUFamily is the metric family name of cgroup CPU accounting.
+	CPUFamily = "cpu"
+	// CPULabel is the label key of the CPU number of a sample.
+	CPULabel = "cpu"
+)
+
+// cpuStatV1 are the cgroup v1 cpu.stat throttling counters reported.
+var cpuStatV1 = []cgroupKey{
+	{file: "cpu.stat", key: "nr_periods", name: "nr_periods"},
+	{file: "cpu.stat", key: "nr_throttled", name: "nr_throttled"},
+	{file: "cpu.stat", key: "throttled_time", name: "throttled_time"},
+}
+
+// cpuStatV2 are the cgroup v2 cpu.stat counters reported, with the same names
+// and units, nanoseconds, as in cgroup v1.
+var cpuStatV2 = []cgroupKey{
+	{file: "cpu.stat", key: "usage_usec", name: "usage", scale: 1000},
+	{file: "cpu.stat", key: "nr_periods", name: "nr_periods"},
+	{file: "cpu.stat", key: "nr_throttled", name: "nr_throttled"},
+	{file: "cpu.stat", key: "throttled_usec", name: "throttled_time", scale: 1000},
+}
+
+// CPUCollector collects the cgroup CPU accounting of containers.
+type CPUCollector struct {
+	mode u.CgroupMode
+}
+
+// NewCPUCollector returns a CPUCollector of the given layout of the cgroup
+// hierarchies.
+func NewCPUCollector(mode u.CgroupMode) *CPUCollector {
+	return &CPUCollector{mode: mode}
+}
+
+func (cc *CPUCollector) Name() string {
+	return CPUFamily
+}
+
+func (cc *CPUCollector) Discover(cont *Container) error {
+	return cont.UpdateCgroupPaths()
+}
+
+// Collect returns the CPU time used by the container, in nanoseconds, in
+// total and per CPU, and its throttling counters. All values are reported as
+// the difference since the last collection. The per CPU usage is not
+// available on cgroup v2.
+func (cc *CPUCollector) Collect(cont *Container) ([]Sample, error) {
+	f := cont.Family(CPUFamily)
+	if cc.mode == u.CgroupV2 {
+		dir := cont.UnifiedCgroupDir(cc.mode)
+		if dir == "" {
+			return nil, errNoCgroup
+		}
+		return storeCgroupKeys(f, cont, dir, cpuStatV2), nil
+	}
+
+	cpuacct, cpu := cont.CgroupDir(cc.mode, "cpuacct"), cont.CgroupDir(cc.mode, "cpu")
+	if cpuacct == "" || cpu == "" {
+		return nil, errNoCgroup
+	}
+	var samples []Sample
+
+	usage, err := u.ReadCgroupInt64(cpuacct, "cpuacct.usage")
+	if err != nil {
+		return nil, err
+	}
+	samples = append(samples, f.Update(cont, "usage", nil, usage))
+
+	if perCPU, err := u.ReadCgroupInt64s(cpuacct, "cpuacct.usage_percpu"); err == nil {
+		for i, usage := range perCPU {
+			labels := map[string]string{CPULabel: strconv.Itoa(i)}
+			samples = append(samples, f.Update(cont, "usage_percpu", labels, usage))
+		}
+	} else {
+		log.Debug("Error while reading per CPU usage of %s: %s", cont.DockerID, err)
+	}
+
+	return append(samples, storeCgroupKeys(f, cont, cpu, cpuStatV1)...), nil
+}
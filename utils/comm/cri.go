@@ -0,0 +1,286 @@
+package comm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/google.golang.org/grpc"
+	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/google.golang.org/grpc/codes"
+	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/google.golang.org/grpc/credentials/insecure"
+	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/google.golang.org/grpc/status"
+	runtimeapi "github.com/cilium-team/docker-collector/Godeps/_workspace/src/k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+const (
+	// CRIRuntimeName is the name of the runtimes implementing the Kubernetes
+	// Container Runtime Interface, such as containerd and CRI-O.
+	CRIRuntimeName     = "cri"
+	criDefaultEndpoint = "unix:///run/containerd/containerd.sock"
+	criTimeout         = 10 * time.Second
+	criPollInterval    = 2 * time.Second
+	// criMaxMessageSize is the maximum size of the messages received, such
+	// as the list of all containers.
+	criMaxMessageSize = 16 << 20
+	k8sContainerLabel = "io.kubernetes.container.name"
+)
+
+// CRIRuntime is a runtime implementing the Kubernetes Container Runtime
+// Interface, reached with gRPC over the unix socket of its endpoint. Events
+// are read from the GetContainerEvents stream or, for runtimes without it,
+// synthesized by polling the list of containers.
+type CRIRuntime struct {
+	client runtimeapi.RuntimeServiceClient
+}
+
+// NewCRIRuntime returns a client of the CRI endpoint set in the
+// CONTAINER_RUNTIME_ENDPOINT environment variable, or of containerd if not
+// set.
+func NewCRIRuntime() (*CRIRuntime, error) {
+	endpoint := os.Getenv("CONTAINER_RUNTIME_ENDPOINT")
+	if endpoint == "" {
+		endpoint = criDefaultEndpoint
+	}
+	socket, err := criSocketPath(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return newCRIRuntimeAt(socket)
+}
+
+// criSocketPath returns the path of the unix socket of the given endpoint,
+// such as unix:///run/containerd/containerd.sock.
+func criSocketPath(endpoint string) (string, error) {
+	if strings.HasPrefix(endpoint, "unix://") {
+		endpoint = strings.TrimPrefix(endpoint, "unix://")
+	} else if strings.Contains(endpoint, "://") {
+		return "", fmt.Errorf("invalid runtime endpoint '%s', only unix sockets are supported", endpoint)
+	}
+	if endpoint == "" {
+		return "", fmt.Errorf("invalid empty runtime endpoint")
+	}
+	return endpoint, nil
+}
+
+// newCRIRuntimeAt returns a client of the CRI runtime listening on the given
+// unix socket. The connection is established on the first call.
+func newCRIRuntimeAt(socket string) (*CRIRuntime, error) {
+	conn, err := grpc.NewClient("unix:"+socket,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(criMaxMessageSize)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &CRIRuntime{client: runtimeapi.NewRuntimeServiceClient(conn)}, nil
+}
+
+func (cr *CRIRuntime) Name() string {
+	return CRIRuntimeName
+}
+
+func (cr *CRIRuntime) Ping() error {
+	ctx, cancel := context.WithTimeout(context.Background(), criTimeout)
+	defer cancel()
+	_, err := cr.client.Version(ctx, &runtimeapi.VersionRequest{Version: "v1"})
+	return err
+}
+
+func (cr *CRIRuntime) listContainers() ([]*runtimeapi.Container, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), criTimeout)
+	defer cancel()
+	resp, err := cr.client.ListContainers(ctx, &runtimeapi.ListContainersRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Containers, nil
+}
+
+func (cr *CRIRuntime) ListContainers() ([]RuntimeContainer, error) {
+	criContainers, err := cr.listContainers()
+	if err != nil {
+		return nil, err
+	}
+	containers := make([]RuntimeContainer, 0, len(criContainers))
+	for _, cc := range criContainers {
+		containers = append(containers, RuntimeContainer{
+			ID:   cc.Id,
+			Name: criContainerName(cc.GetMetadata().GetName(), cc.Labels),
+		})
+	}
+	return containers, nil
+}
+
+// InspectContainer returns the verbose status of the container. Its PID is
+// the one in the JSON "info" of containerd and CRI-O.
+func (cr *CRIRuntime) InspectContainer(id string) (*RuntimeContainer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), criTimeout)
+	defer cancel()
+	resp, err := cr.client.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: id, Verbose: true})
+	if err != nil {
+		return nil, err
+	}
+	cs := resp.Status
+	if cs == nil {
+		return nil, fmt.Errorf("container status of %s without status", id)
+	}
+	var verbose struct {
+		PID int `json:"pid"`
+	}
+	if i := resp.Info["info"]; i != "" {
+		if err := json.Unmarshal([]byte(i), &verbose); err != nil {
+			log.Debug("Error while reading the info of container %s: %s", id, err)
+		}
+	}
+	rc := &RuntimeContainer{
+		ID:      cs.Id,
+		Name:    criContainerName(cs.GetMetadata().GetName(), cs.Labels),
+		PID:     verbose.PID,
+		Running: cs.State == runtimeapi.ContainerState_CONTAINER_RUNNING,
+		Image:   cs.GetImage().GetImage(),
+		Labels:  cs.Labels,
+	}
+	// imageRef is either the image ID or its repository digest.
+	if strings.Contains(cs.ImageRef, "@") {
+		rc.ImageDigest = cs.ImageRef
+	} else {
+		rc.ImageID = cs.ImageRef
+	}
+	return rc, nil
+}
+
+// MonitorEvents sends a start event for containers that started running, a
+// die event for containers that stopped running and a destroy event for
+// containers that were removed. They are read from the container events
+// stream of the runtime, which is opened again, after listing the containers
+// to find the events missed, if it breaks. Runtimes without container events
+// are polled instead.
+func (cr *CRIRuntime) MonitorEvents(events chan<- RuntimeEvent) error {
+	criContainers, err := cr.listContainers()
+	if err != nil {
+		return err
+	}
+	running := criRunningStates(criContainers)
+	go func() {
+		for {
+			err := cr.watchEvents(running, events)
+			if status.Code(err) == codes.Unimplemented {
+				log.Info("Runtime without container events, listing containers every %s", criPollInterval)
+				cr.pollEvents(running, events)
+				return
+			}
+			log.Error("Error while watching container events: %s", err)
+			time.Sleep(criPollInterval)
+			running = cr.resyncEvents(running, events)
+		}
+	}()
+	return nil
+}
+
+// watchEvents sends the events of the container events stream until it
+// breaks, keeping the given running states up to date.
+func (cr *CRIRuntime) watchEvents(running map[string]bool, events chan<- RuntimeEvent) error {
+	stream, err := cr.client.GetContainerEvents(context.Background(), &runtimeapi.GetEventsRequest{})
+	if err != nil {
+		return err
+	}
+	for {
+		ev, err := stream.Recv()
+		if err == io.EOF {
+			return fmt.Errorf("container events stream closed")
+		}
+		if err != nil {
+			return err
+		}
+		// Pod sandboxes aren't containers of the runtime.
+		if ev.ContainerId == "" || ev.ContainerId == ev.GetPodSandboxStatus().GetId() {
+			continue
+		}
+		var eventStatus string
+		switch ev.ContainerEventType {
+		case runtimeapi.ContainerEventType_CONTAINER_STARTED_EVENT:
+			eventStatus = EventStart
+			running[ev.ContainerId] = true
+		case runtimeapi.ContainerEventType_CONTAINER_STOPPED_EVENT:
+			eventStatus = EventDie
+			running[ev.ContainerId] = false
+		case runtimeapi.ContainerEventType_CONTAINER_DELETED_EVENT:
+			eventStatus = EventDestroy
+			delete(running, ev.ContainerId)
+		default:
+			continue
+		}
+		events <- RuntimeEvent{ID: ev.ContainerId, Status: eventStatus}
+	}
+}
+
+// pollEvents lists the containers every criPollInterval and sends the events
+// between two consecutive listings.
+func (cr *CRIRuntime) pollEvents(running map[string]bool, events chan<- RuntimeEvent) {
+	ticker := time.NewTicker(criPollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		running = cr.resyncEvents(running, events)
+	}
+}
+
+// resyncEvents lists the containers, sends the events since the given running
+// states and returns the new ones.
+func (cr *CRIRuntime) resyncEvents(running map[string]bool, events chan<- RuntimeEvent) map[string]bool {
+	criContainers, err := cr.listContainers()
+	if err != nil {
+		log.Error("Error while listing containers: %s", err)
+		return running
+	}
+	newRunning := criRunningStates(criContainers)
+	for _, event := range criEvents(running, newRunning) {
+		events <- event
+	}
+	return newRunning
+}
+
+// criRunningStates returns whether each of the given containers is running,
+// keyed by container ID.
+func criRunningStates(criContainers []*runtimeapi.Container) map[string]bool {
+	running := make(map[string]bool, len(criContainers))
+	for _, cc := range criContainers {
+		running[cc.Id] = cc.State == runtimeapi.ContainerState_CONTAINER_RUNNING
+	}
+	return running
+}
+
+// criEvents returns the events between two consecutive listings of the
+// containers.
+func criEvents(running, newRunning map[string]bool) []RuntimeEvent {
+	var events []RuntimeEvent
+	for id, isRunning := range newRunning {
+		if isRunning && !running[id] {
+			events = append(events, RuntimeEvent{ID: id, Status: EventStart})
+		} else if !isRunning && running[id] {
+			events = append(events, RuntimeEvent{ID: id, Status: EventDie})
+		}
+	}
+	for id := range running {
+		if _, ok := newRunning[id]; !ok {
+			events = append(events, RuntimeEvent{ID: id, Status: EventDestroy})
+		}
+	}
+	return events
+}
+
+// criContainerName returns the name of a container with the given name and
+// labels prefixed by its pod name, if any, since container names are only
+// unique inside a pod.
+func criContainerName(name string, labels map[string]string) string {
+	if name == "" {
+		name = labels[k8sContainerLabel]
+	}
+	if pod := labels[k8sPodNameLabel]; pod != "" {
+		return "/" + pod + "_" + name
+	}
+	return "/" + name
+}
@@ -0,0 +1,264 @@
+package comm
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/google.golang.org/grpc"
+	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/google.golang.org/grpc/codes"
+	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/google.golang.org/grpc/status"
+	runtimeapi "github.com/cilium-team/docker-collector/Godeps/_workspace/src/k8s.io/cri-api/pkg/apis/runtime/v1"
+)
+
+const criContainerID = "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+
+// fakeCRIServer is a runtime service whose methods not set are
+// unimplemented.
+type fakeCRIServer struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+	version         func(*runtimeapi.VersionRequest) (*runtimeapi.VersionResponse, error)
+	listContainers  func(*runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error)
+	containerStatus func(*runtimeapi.ContainerStatusRequest) (*runtimeapi.ContainerStatusResponse, error)
+	events          []*runtimeapi.ContainerEventResponse
+}
+
+func (s *fakeCRIServer) Version(ctx context.Context, req *runtimeapi.VersionRequest) (*runtimeapi.VersionResponse, error) {
+	if s.version == nil {
+		return s.UnimplementedRuntimeServiceServer.Version(ctx, req)
+	}
+	return s.version(req)
+}
+
+func (s *fakeCRIServer) ListContainers(ctx context.Context, req *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	if s.listContainers == nil {
+		return s.UnimplementedRuntimeServiceServer.ListContainers(ctx, req)
+	}
+	return s.listContainers(req)
+}
+
+func (s *fakeCRIServer) ContainerStatus(ctx context.Context, req *runtimeapi.ContainerStatusRequest) (*runtimeapi.ContainerStatusResponse, error) {
+	if s.containerStatus == nil {
+		return s.UnimplementedRuntimeServiceServer.ContainerStatus(ctx, req)
+	}
+	return s.containerStatus(req)
+}
+
+func (s *fakeCRIServer) GetContainerEvents(req *runtimeapi.GetEventsRequest, stream runtimeapi.RuntimeService_GetContainerEventsServer) error {
+	if s.events == nil {
+		return s.UnimplementedRuntimeServiceServer.GetContainerEvents(req, stream)
+	}
+	for _, ev := range s.events {
+		if err := stream.Send(ev); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// newCRIServer returns a client of the given runtime service, served with
+// gRPC on a unix socket like containerd and CRI-O.
+func newCRIServer(t *testing.T, fake *fakeCRIServer) *CRIRuntime {
+	socket := filepath.Join(t.TempDir(), "cri.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	srv := grpc.NewServer()
+	runtimeapi.RegisterRuntimeServiceServer(srv, fake)
+	go srv.Serve(l)
+	t.Cleanup(srv.Stop)
+	cr, err := newCRIRuntimeAt(socket)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return cr
+}
+
+// criTestEvent returns an event of the container with the given ID of the
+// given pod sandbox.
+func criTestEvent(id string, typ runtimeapi.ContainerEventType, sandboxID string) *runtimeapi.ContainerEventResponse {
+	return &runtimeapi.ContainerEventResponse{
+		ContainerId:        id,
+		ContainerEventType: typ,
+		PodSandboxStatus:   &runtimeapi.PodSandboxStatus{Id: sandboxID},
+	}
+}
+
+func TestCRIInspectContainer(t *testing.T) {
+	cr := newCRIServer(t, &fakeCRIServer{
+		containerStatus: func(req *runtimeapi.ContainerStatusRequest) (*runtimeapi.ContainerStatusResponse, error) {
+			if req.ContainerId != criContainerID || !req.Verbose {
+				t.Errorf("unexpected request: %v", req)
+			}
+			return &runtimeapi.ContainerStatusResponse{
+				Status: &runtimeapi.ContainerStatus{
+					Id:        criContainerID,
+					Metadata:  &runtimeapi.ContainerMetadata{Name: "web"},
+					State:     runtimeapi.ContainerState_CONTAINER_RUNNING,
+					CreatedAt: 1700000000000000000,
+					Image:     &runtimeapi.ImageSpec{Image: "docker.io/library/nginx:1.25"},
+					ImageRef:  "docker.io/library/nginx@sha256:0d17b565c37bcbd895e9d92315a05c1c3c9a29f762b011a10c54a66cd53c9b31",
+					Labels: map[string]string{
+						"io.kubernetes.container.name": "web",
+						"io.kubernetes.pod.name":       "web-5d8f7c9b6-x2x9z",
+						"io.kubernetes.pod.namespace":  "shop",
+					},
+				},
+				Info: map[string]string{"info": `{"pid": 4242, "sandboxID": "sandbox"}`},
+			}, nil
+		},
+	})
+	rc, err := cr.InspectContainer(criContainerID)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rc.Name != "/web-5d8f7c9b6-x2x9z_web" {
+		t.Errorf("name:\ngot  %s\nwant %s", rc.Name, "/web-5d8f7c9b6-x2x9z_web")
+	}
+	if rc.PID != 4242 || !rc.Running {
+		t.Errorf("state:\ngot  %d %t\nwant %d %t", rc.PID, rc.Running, 4242, true)
+	}
+	if rc.Image != "docker.io/library/nginx:1.25" || !strings.HasPrefix(rc.ImageDigest, "docker.io/library/nginx@sha256:") {
+		t.Errorf("image:\ngot  %s %s", rc.Image, rc.ImageDigest)
+	}
+	if rc.Labels[k8sPodNamespaceLabel] != "shop" {
+		t.Errorf("labels:\ngot  %v", rc.Labels)
+	}
+}
+
+func TestCRIListContainers(t *testing.T) {
+	cr := newCRIServer(t, &fakeCRIServer{
+		version: func(req *runtimeapi.VersionRequest) (*runtimeapi.VersionResponse, error) {
+			return &runtimeapi.VersionResponse{RuntimeName: "containerd"}, nil
+		},
+		listContainers: func(req *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+			return &runtimeapi.ListContainersResponse{Containers: []*runtimeapi.Container{
+				{Id: "a", PodSandboxId: "sandbox", Metadata: &runtimeapi.ContainerMetadata{Name: "web"}, State: runtimeapi.ContainerState_CONTAINER_RUNNING},
+				{Id: "b", PodSandboxId: "sandbox", Metadata: &runtimeapi.ContainerMetadata{Name: "job"}, State: runtimeapi.ContainerState_CONTAINER_EXITED},
+			}}, nil
+		},
+	})
+	if err := cr.Ping(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	containers, err := cr.ListContainers()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(containers) != 2 || containers[0].ID != "a" || containers[0].Name != "/web" || containers[1].Name != "/job" {
+		t.Errorf("containers:\ngot  %+v", containers)
+	}
+	criContainers, err := cr.listContainers()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	running := criRunningStates(criContainers)
+	if !running["a"] || running["b"] {
+		t.Errorf("running states:\ngot  %v", running)
+	}
+}
+
+func TestCRIErrors(t *testing.T) {
+	cr := newCRIServer(t, &fakeCRIServer{
+		containerStatus: func(req *runtimeapi.ContainerStatusRequest) (*runtimeapi.ContainerStatusResponse, error) {
+			return nil, status.Error(codes.NotFound, "container not found")
+		},
+	})
+	_, err := cr.InspectContainer("missing")
+	if s, _ := status.FromError(err); s.Code() != codes.NotFound || s.Message() != "container not found" {
+		t.Errorf("error:\ngot  %v\nwant %s", err, "rpc error: code = NotFound desc = container not found")
+	}
+	if _, err := cr.ListContainers(); status.Code(err) != codes.Unimplemented {
+		t.Errorf("error of unimplemented method:\ngot  %v", err)
+	}
+	missing, err := newCRIRuntimeAt(filepath.Join(t.TempDir(), "none.sock"))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := missing.Ping(); err == nil {
+		t.Errorf("missing socket should return an error")
+	}
+}
+
+func TestCRIWatchEvents(t *testing.T) {
+	cr := newCRIServer(t, &fakeCRIServer{
+		events: []*runtimeapi.ContainerEventResponse{
+			criTestEvent("sandbox", runtimeapi.ContainerEventType_CONTAINER_STARTED_EVENT, "sandbox"),
+			criTestEvent("a", runtimeapi.ContainerEventType_CONTAINER_CREATED_EVENT, "sandbox"),
+			criTestEvent("a", runtimeapi.ContainerEventType_CONTAINER_STARTED_EVENT, "sandbox"),
+			criTestEvent("b", runtimeapi.ContainerEventType_CONTAINER_STOPPED_EVENT, "sandbox"),
+			criTestEvent("c", runtimeapi.ContainerEventType_CONTAINER_DELETED_EVENT, "sandbox"),
+		},
+	})
+	running := map[string]bool{"b": true, "c": false}
+	events := make(chan RuntimeEvent, 10)
+	err := cr.watchEvents(running, events)
+	if err == nil || status.Code(err) == codes.Unimplemented {
+		t.Errorf("closed stream should return an error:\ngot  %v", err)
+	}
+	close(events)
+	var got []RuntimeEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	want := []RuntimeEvent{{"a", EventStart}, {"b", EventDie}, {"c", EventDestroy}}
+	if len(got) != len(want) {
+		t.Fatalf("events:\ngot  %v\nwant %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d:\ngot  %v\nwant %v", i, got[i], want[i])
+		}
+	}
+	if !running["a"] || running["b"] {
+		t.Errorf("running states:\ngot  %v", running)
+	}
+	if _, ok := running["c"]; ok {
+		t.Errorf("deleted container should be forgotten")
+	}
+
+	// Runtimes without events are polled.
+	cr = newCRIServer(t, &fakeCRIServer{})
+	if err := cr.watchEvents(running, events); status.Code(err) != codes.Unimplemented {
+		t.Errorf("error of runtime without events:\ngot  %v", err)
+	}
+}
+
+func TestCRIEvents(t *testing.T) {
+	running := map[string]bool{"a": true, "b": true, "c": false, "d": true}
+	newRunning := map[string]bool{"a": true, "b": false, "c": true, "e": true}
+	got := map[string]string{}
+	for _, event := range criEvents(running, newRunning) {
+		got[event.ID] = event.Status
+	}
+	want := map[string]string{"b": EventDie, "c": EventStart, "d": EventDestroy, "e": EventStart}
+	if len(got) != len(want) {
+		t.Errorf("events:\ngot  %v\nwant %v", got, want)
+	}
+	for id, status := range want {
+		if got[id] != status {
+			t.Errorf("event of %s:\ngot  %s\nwant %s", id, got[id], status)
+		}
+	}
+}
+
+func TestCRISocketPath(t *testing.T) {
+	tests := []struct {
+		endpoint, want string
+		valid          bool
+	}{
+		{"unix:///run/containerd/containerd.sock", "/run/containerd/containerd.sock", true},
+		{"/var/run/crio/crio.sock", "/var/run/crio/crio.sock", true},
+		{"tcp://127.0.0.1:1234", "", false},
+		{"unix://", "", false},
+	}
+	for _, tt := range tests {
+		got, err := criSocketPath(tt.endpoint)
+		if got != tt.want || (err == nil) != tt.valid {
+			t.Errorf("socket of %s:\ngot  %q %v\nwant %q", tt.endpoint, got, err, tt.want)
+		}
+	}
+}
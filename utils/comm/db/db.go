@@ -1,6 +1,8 @@
 package db
 
 import (
+	"bytes"
+	"io"
 	"strings"
 
 	uc "github.com/cilium-team/docker-collector/utils/comm"
@@ -17,7 +19,7 @@ const (
 	NetworkInterfacesTableName = "network_interfaces"
 	NetworkStatsTableName      = "network_stats"
 	NodeTableName              = "node_stats"
-	DBDrivers                  = "elasticsearch"
+	DBDrivers                  = "elasticsearch|elasticsearch-bulk|prometheus|influxdb|statsd|graphite"
 )
 
 func IsValidDBDriver(dbDriver string) bool {
@@ -33,6 +35,14 @@ func InitDb(dbType, indexName, configPath string) error {
 	switch dbType {
 	case "elasticsearch":
 		return InitElasticDb(indexName, configPath)
+	case "elasticsearch-bulk":
+		return InitElasticBulkDb(indexName, configPath)
+	case "prometheus":
+		return nil
+	case "influxdb":
+		return InitInfluxDb(indexName)
+	case "statsd", "graphite":
+		return nil
 	default:
 		return InitElasticDb(indexName, configPath)
 	}
@@ -46,6 +56,16 @@ func NewConnOf(dbType string, indexName string, configPath string) (Db, error) {
 	switch dbType {
 	case "elasticsearch":
 		return NewElasticConn(indexName, configPath)
+	case "elasticsearch-bulk":
+		return NewElasticBulkConn(indexName, configPath)
+	case "prometheus":
+		return NewPromConn()
+	case "influxdb":
+		return NewInfluxConn(indexName)
+	case "statsd":
+		return NewStatsdConn(indexName)
+	case "graphite":
+		return NewGraphiteConn(indexName)
 	default:
 		return NewElasticConn(indexName, configPath)
 	}
@@ -53,7 +73,40 @@ func NewConnOf(dbType string, indexName string, configPath string) (Db, error) {
 
 type Db interface {
 	Close()
-	UpdateNode(*uc.Node) error
+	UpdateNode(*uc.Node, []uc.Sample) error
 	CreateNode(*uc.Node) error
 	CreateCluster() error
 }
+
+// writePacked writes the given lines to w, packed in writes of at most size
+// bytes so each datagram holds as many lines as possible. Longer lines are
+// written alone.
+func writePacked(w io.Writer, lines []string, size int) error {
+	var buf bytes.Buffer
+	for _, line := range lines {
+		if buf.Len() != 0 && buf.Len()+len(line)+1 > size {
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+			buf.Reset()
+		}
+		buf.WriteString(line)
+		buf.WriteByte('\n')
+	}
+	if buf.Len() != 0 {
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampleNodeName returns the name of the node of the container of the given
+// sample, or the name of the given node if the sample has no container or its
+// container has no node name, such as when the host name couldn't be read.
+func sampleNodeName(node *uc.Node, sample uc.Sample) string {
+	if cont := sample.Container; cont != nil && cont.NodeName != "" {
+		return cont.NodeName
+	}
+	return node.Name
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"testing"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+// testSamples returns a node with a container and samples of the container
+// and of the node.
+func testSamples() (*uc.Node, []uc.Sample) {
+	node := &uc.Node{Name: "node1", Containers: []uc.Container{{Name: "/web", NodeName: "node1", IsActive: true}}}
+	cont := &node.Containers[0]
+	cont.Metadata.Image = "nginx:1.9"
+	labels := map[string]string{uc.InterfaceLabel: "eth0"}
+	return node, []uc.Sample{
+		{Family: uc.NetworkFamily, Name: "rx_bytes", Kind: uc.Counter, Labels: labels, Value: 10, ValueRead: 1500, Container: cont},
+		{Family: uc.NetworkFamily, Name: "tx_bytes", Kind: uc.Counter, Labels: labels, Value: 20, ValueRead: 3000, Container: cont},
+		{Family: "system", Name: "load1", Kind: uc.Gauge, Value: 42},
+	}
+}
+
+func TestSampleNodeName(t *testing.T) {
+	node, samples := testSamples()
+	if got := sampleNodeName(node, samples[0]); got != "node1" {
+		t.Errorf("node of container sample:\ngot %s\nwant %s", got, "node1")
+	}
+	node.Name = "host1"
+	if got := sampleNodeName(node, samples[2]); got != "host1" {
+		t.Errorf("node of node sample:\ngot %s\nwant %s", got, "host1")
+	}
+	// Containers whose host name couldn't be read
+	node.Containers[0].NodeName = ""
+	if got := sampleNodeName(node, samples[0]); got != "host1" {
+		t.Errorf("node of container sample without node name:\ngot %s\nwant %s", got, "host1")
+	}
+}
@@ -17,18 +17,24 @@ type logstashConn struct {
 	lsConn net.Conn
 }
 
+// LogConn sends the documents to logstash or, if bulkIndexer is set, indexes
+// them directly in ElasticSearch.
 type LogConn struct {
 	*elastic.Client
 	*logstashConn
+	*bulkIndexer
 	indexName  string
 	configPath string
 }
 
 type ENode struct {
-	Name      string
-	CreatedAt time.Time
-	UpdatedAt time.Time
-	DeletedAt *time.Time
+	Family           string
+	NodeName         string
+	Containers       int
+	ActiveContainers int
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	DeletedAt        *time.Time `json:",omitempty"`
 }
 
 type EContainer struct {
@@ -42,16 +48,71 @@ type EContainer struct {
 }
 
 type ENetworkInterface struct {
-	Name string
+	Name       string
+	Type       string `json:",omitempty"`
+	OperState  string
+	Carrier    bool
+	MTU        int
+	MACAddress string   `json:",omitempty"`
+	Speed      int64    `json:",omitempty"`
+	Addresses  []string `json:",omitempty"`
+	// HostInterface and Bridge connect the interface of a container to the
+	// network of the node.
+	HostInterface string `json:",omitempty"`
+	Bridge        string `json:",omitempty"`
+	BridgeType    string `json:",omitempty"`
+}
+
+// ETopology lists, per node, the host interfaces and bridges the interfaces of
+// each container are connected to.
+type ETopology struct {
+	Family    string
+	NodeName  string
+	Links     []ETopologyLink
+	UpdatedAt time.Time
+}
+
+type ETopologyLink struct {
+	ContainerDockerID string
+	ContainerName     string
+	NetworkInterface  string
+	HostInterface     string
+	Bridge            string `json:",omitempty"`
+	BridgeType        string `json:",omitempty"`
+}
+
+// EKubernetes is the pod a container belongs to.
+type EKubernetes struct {
+	Namespace    string
+	PodName      string
+	PodUID       string
+	WorkloadKind string            `json:",omitempty"`
+	Workload     string            `json:",omitempty"`
+	PodLabels    map[string]string `json:",omitempty"`
 }
 
 type ENetworkStat struct {
 	Value                int64
+	Family               string
+	Kind                 string
 	Name                 string
-	ContainerDockerID    string
-	ContainerName        string
+	ContainerDockerID    string            `json:",omitempty"`
+	ContainerName        string            `json:",omitempty"`
+	Image                string            `json:",omitempty"`
+	ImageDigest          string            `json:",omitempty"`
+	ComposeProject       string            `json:",omitempty"`
+	ComposeService       string            `json:",omitempty"`
+	SwarmServiceID       string            `json:",omitempty"`
+	SwarmTaskID          string            `json:",omitempty"`
+	ContainerLabels      map[string]string `json:",omitempty"`
+	Kubernetes           *EKubernetes      `json:",omitempty"`
+	NetNSGroup           string            `json:",omitempty"`
+	HostNetwork          bool              `json:",omitempty"`
 	NodeName             string
-	NetworkInterfaceName string
+	NetworkInterfaceName string             `json:",omitempty"`
+	NetworkInterface     *ENetworkInterface `json:",omitempty"`
+	DeviceName           string             `json:",omitempty"`
+	Labels               map[string]string  `json:",omitempty"`
 	UpdatedAt            time.Time
 }
 
@@ -63,6 +124,8 @@ const (
 	elasticDefaultIndex = "docker-collector"
 	logstashDefaultPort = "8080"
 	logstashDefaultIP   = "logstash"
+	topologyFamily      = "topology"
+	nodeFamily          = "node"
 )
 
 var (
@@ -75,7 +138,18 @@ func InitElasticDb(indexName, configPath string) error {
 	if err != nil {
 		return err
 	}
-	defer c.Close()
+	return initElasticIndex(c)
+}
+
+func InitElasticBulkDb(indexName, configPath string) error {
+	c, err := NewElasticBulkConn(indexName, configPath)
+	if err != nil {
+		return err
+	}
+	return initElasticIndex(c)
+}
+
+func initElasticIndex(c LogConn) error {
 
 	//	if _, err = c.DeleteIndex(c.indexName + `-*`).Do(); err != nil {
 	//		return err
@@ -108,14 +182,118 @@ func setMappings(c LogConn) error {
 					"type":  "string",
 					"index": "not_analyzed",
 				},
+				"Image": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
+				"ImageDigest": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
+				"ComposeProject": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
+				"ComposeService": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
+				"SwarmServiceID": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
+				"SwarmTaskID": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
+				"NetNSGroup": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
 				"NetworkInterfaceName": map[string]string{
 					"type":  "string",
 					"index": "not_analyzed",
 				},
+				"DeviceName": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
 				"Name": map[string]string{
 					"type":  "string",
 					"index": "not_analyzed",
 				},
+				"Family": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
+				"Kind": map[string]string{
+					"type":  "string",
+					"index": "not_analyzed",
+				},
+				"NetworkInterface": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"Type": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"OperState": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"MACAddress": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"Addresses": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"HostInterface": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"Bridge": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+					},
+				},
+				"Kubernetes": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"Namespace": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"PodName": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"Workload": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+					},
+				},
+				"Links": map[string]interface{}{
+					"properties": map[string]interface{}{
+						"ContainerName": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"NetworkInterface": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"HostInterface": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+						"Bridge": map[string]string{
+							"type":  "string",
+							"index": "not_analyzed",
+						},
+					},
+				},
 			},
 		},
 	}
@@ -123,8 +301,7 @@ func setMappings(c LogConn) error {
 	return err
 }
 
-func NewElasticConn(indexName string, configPath string) (LogConn, error) {
-	log.Debug("")
+func elasticAddr() (string, string) {
 	elasticPort := os.Getenv("ELASTIC_PORT")
 	if elasticPort == "" {
 		elasticPort = elasticDefaultPort
@@ -133,6 +310,12 @@ func NewElasticConn(indexName string, configPath string) (LogConn, error) {
 	if elasticIP == "" {
 		elasticIP = elasticDefaultIP
 	}
+	return elasticIP, elasticPort
+}
+
+func NewElasticConn(indexName string, configPath string) (LogConn, error) {
+	log.Debug("")
+	elasticIP, elasticPort := elasticAddr()
 	logstashPort := os.Getenv("LOGSTASH_PORT")
 	if logstashPort == "" {
 		logstashPort = logstashDefaultPort
@@ -147,6 +330,20 @@ func NewElasticConn(indexName string, configPath string) (LogConn, error) {
 	return NewConnTo(elasticIP, elasticPort, logstashIP, logstashPort, indexName, configPath)
 }
 
+// NewElasticBulkConn returns a connection that indexes the documents directly
+// in ElasticSearch, without logstash.
+func NewElasticBulkConn(indexName string, configPath string) (LogConn, error) {
+	log.Debug("")
+	elasticIP, elasticPort := elasticAddr()
+	if indexName == "" {
+		indexName = elasticDefaultIndex
+	}
+	return NewConnTo(elasticIP, elasticPort, "", "", indexName, configPath)
+}
+
+// NewConnTo returns the connection to the given ElasticSearch. Documents are
+// sent through the given logstash or, if logstashIP is empty, indexed with the
+// Bulk API.
 func NewConnTo(elasticIP, elasticPort, logstashIP, logstashPort, indexName, configPath string) (LogConn, error) {
 	log.Debug("")
 	var outerr error
@@ -182,11 +379,16 @@ func NewConnTo(elasticIP, elasticPort, logstashIP, logstashPort, indexName, conf
 		}
 		ec.indexName = indexName
 		ec.configPath = configPath
+		if outerr != nil {
+			return
+		}
+		if logstashIP == "" {
+			ec.bulkIndexer = newBulkIndexer(ec.Client, indexName)
+			return
+		}
 		lc := logstashConn{}
 		ec.logstashConn = &lc
-		if outerr == nil {
-			outerr = ec.connectToLogstash(logstashIP + ":" + logstashPort)
-		}
+		outerr = ec.connectToLogstash(logstashIP + ":" + logstashPort)
 
 	})
 	return ec, outerr
@@ -217,42 +419,128 @@ func (c LogConn) reconnectToLogstash() error {
 }
 
 func (c LogConn) Close() {
+	if c.bulkIndexer != nil {
+		c.bulkIndexer.Close()
+	}
 }
 
-func convertToElasticNetStat(cont uc.Container, netInt uc.NetworkInterface, stat uc.NetworkStat) ENetworkStat {
-	return ENetworkStat{
-		Value:                stat.CurrentValue,
-		Name:                 stat.Name,
-		ContainerDockerID:    cont.DockerID,
-		ContainerName:        cont.NodeName + cont.Name,
-		NodeName:             cont.NodeName,
-		NetworkInterfaceName: netInt.Name,
+func convertToElasticNetStat(node *uc.Node, sample uc.Sample) ENetworkStat {
+	enetstat := ENetworkStat{
+		Value:                sample.Value,
+		Family:               sample.Family,
+		Kind:                 sample.Kind.String(),
+		Name:                 sample.Name,
+		NodeName:             node.Name,
+		NetworkInterfaceName: sample.Labels[uc.InterfaceLabel],
+		DeviceName:           sample.Labels[uc.DeviceLabel],
+		Labels:               sample.Labels,
 	}
+	if netInter := sample.Interface; netInter != nil {
+		enetstat.NetworkInterface = &ENetworkInterface{
+			Name:          netInter.Name,
+			Type:          netInter.Type,
+			OperState:     netInter.OperState,
+			Carrier:       netInter.Carrier,
+			MTU:           netInter.MTU,
+			MACAddress:    netInter.MACAddress,
+			Speed:         netInter.Speed,
+			Addresses:     netInter.Addresses,
+			HostInterface: netInter.HostInterface,
+			Bridge:        netInter.Bridge,
+			BridgeType:    netInter.BridgeType,
+		}
+	}
+	if cont := sample.Container; cont != nil {
+		enetstat.ContainerDockerID = cont.DockerID
+		enetstat.ContainerName = cont.NodeName + cont.Name
+		enetstat.Image = cont.Metadata.Image
+		enetstat.ImageDigest = cont.Metadata.ImageDigest
+		enetstat.ComposeProject = cont.Metadata.ComposeProject
+		enetstat.ComposeService = cont.Metadata.ComposeService
+		enetstat.SwarmServiceID = cont.Metadata.SwarmServiceID
+		enetstat.SwarmTaskID = cont.Metadata.SwarmTaskID
+		enetstat.ContainerLabels = cont.Metadata.Labels
+		enetstat.NetNSGroup = cont.NetNSGroup
+		enetstat.HostNetwork = cont.HostNetwork
+		if km := cont.Metadata.Kubernetes; km != nil {
+			enetstat.Kubernetes = &EKubernetes{
+				Namespace:    km.Namespace,
+				PodName:      km.PodName,
+				PodUID:       km.PodUID,
+				WorkloadKind: km.WorkloadKind,
+				Workload:     km.Workload,
+				PodLabels:    km.PodLabels,
+			}
+		}
+		enetstat.NodeName = cont.NodeName
+	}
+	return enetstat
+}
+
+func convertToElasticNode(node *uc.Node) ENode {
+	return ENode{
+		Family:           nodeFamily,
+		NodeName:         node.Name,
+		Containers:       len(node.Containers),
+		ActiveContainers: node.ActiveContainers(),
+		CreatedAt:        node.CreatedAt,
+		UpdatedAt:        node.UpdatedAt,
+		DeletedAt:        node.DeletedAt,
+	}
+}
+
+func convertToElasticTopology(node *uc.Node) ETopology {
+	etopology := ETopology{
+		Family:   topologyFamily,
+		NodeName: node.Name,
+	}
+	for _, link := range node.Topology() {
+		etopology.Links = append(etopology.Links, ETopologyLink{
+			ContainerDockerID: link.ContainerDockerID,
+			ContainerName:     link.ContainerName,
+			NetworkInterface:  link.Interface,
+			HostInterface:     link.HostInterface,
+			Bridge:            link.Bridge,
+			BridgeType:        link.BridgeType,
+		})
+	}
+	return etopology
 }
 
-func (c LogConn) UpdateNode(node *uc.Node) error {
+func (c LogConn) UpdateNode(node *uc.Node, samples []uc.Sample) error {
 	now := time.Now()
 	node.UpdatedAt = now
-	for _, cont := range node.Containers {
-		cont.UpdateLastValue()
-		for _, inter := range cont.NetworkInterfaces {
-			for _, stat := range inter.NetworkStats {
-				enetstat := convertToElasticNetStat(cont, inter, stat)
-				enetstat.UpdatedAt = now
-				enetstatBytes, err := json.Marshal(enetstat)
-				if err != nil {
-					log.Error("error while marshalling '%+v': \"%v\"", enetstat, err)
-				}
-				enetstatBytes = append(enetstatBytes, '\n')
-				_, err = c.lsConn.Write(enetstatBytes)
-				if err != nil {
-					log.Error("error while sending bytes to logstash '%+v': \"%v\"", enetstat, err)
-					if err := c.reconnectToLogstash(); err != nil {
-						log.Error("Fail to reconnect: %#v", err)
-					}
-				}
-			}
-		}
+	for _, sample := range samples {
+		enetstat := convertToElasticNetStat(node, sample)
+		enetstat.UpdatedAt = now
+		c.send(enetstat, now)
 	}
+	c.send(convertToElasticNode(node), now)
+	etopology := convertToElasticTopology(node)
+	etopology.UpdatedAt = now
+	c.send(etopology, now)
 	return nil
 }
+
+// send writes the given document, updated at the given time, to logstash or
+// queues it in the bulkIndexer.
+func (c LogConn) send(doc interface{}, updatedAt time.Time) {
+	if c.bulkIndexer != nil {
+		if err := c.bulkIndexer.add(doc, updatedAt); err != nil {
+			log.Error("error while marshalling '%+v': \"%v\"", doc, err)
+		}
+		return
+	}
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		log.Error("error while marshalling '%+v': \"%v\"", doc, err)
+	}
+	docBytes = append(docBytes, '\n')
+	_, err = c.lsConn.Write(docBytes)
+	if err != nil {
+		log.Error("error while sending bytes to logstash '%+v': \"%v\"", doc, err)
+		if err := c.reconnectToLogstash(); err != nil {
+			log.Error("Fail to reconnect: %#v", err)
+		}
+	}
+}
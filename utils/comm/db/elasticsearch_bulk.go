@@ -0,0 +1,240 @@
+package db
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/gopkg.in/olivere/elastic.v3"
+)
+
+const (
+	bulkDefaultActions       = 1000
+	bulkDefaultSize          = 5 << 20
+	bulkDefaultFlushInterval = 10 * time.Second
+	// bulkMaxPendingFactor times the number of actions, or the size, of a
+	// bulk request is the number of documents, or their size, kept while
+	// ElasticSearch can't be reached.
+	bulkMaxPendingFactor = 10
+	timestampField       = "@timestamp"
+	timestampFormat      = "2006-01-02T15:04:05.000Z07:00"
+)
+
+// bulkDocument is a queued document and the size of its encoding.
+type bulkDocument struct {
+	req  *elastic.BulkIndexRequest
+	size int
+}
+
+// bulkIndexer indexes documents in the daily index through the Bulk API. The
+// queued documents are sent once maxActions of them, or maxBytes of encoded
+// documents, are queued and every flushInterval, in requests of at most
+// maxActions documents and maxBytes.
+type bulkIndexer struct {
+	client        *elastic.Client
+	indexName     string
+	maxActions    int
+	maxBytes      int
+	flushInterval time.Duration
+	// mutex guards pending and pendingBytes, it isn't held while sending
+	// documents so they can still be added.
+	mutex        sync.Mutex
+	pending      []bulkDocument
+	pendingBytes int
+	// flushNow wakes up flushPeriodically when enough documents are
+	// queued, so they are sent without blocking add.
+	flushNow chan struct{}
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// newBulkIndexer returns a bulkIndexer of the given client. The number of
+// documents per request, their size in bytes and the flush interval can be
+// changed with the ELASTIC_BULK_ACTIONS, ELASTIC_BULK_SIZE and
+// ELASTIC_BULK_FLUSH_INTERVAL environment variables.
+func newBulkIndexer(client *elastic.Client, indexName string) *bulkIndexer {
+	log.Debug("")
+	bi := &bulkIndexer{
+		client:        client,
+		indexName:     indexName,
+		maxActions:    bulkDefaultActions,
+		maxBytes:      bulkDefaultSize,
+		flushInterval: bulkDefaultFlushInterval,
+		flushNow:      make(chan struct{}, 1),
+		stop:          make(chan struct{}),
+		done:          make(chan struct{}),
+	}
+	if actions, err := strconv.Atoi(os.Getenv("ELASTIC_BULK_ACTIONS")); err == nil && actions > 0 {
+		bi.maxActions = actions
+	}
+	if size, err := strconv.Atoi(os.Getenv("ELASTIC_BULK_SIZE")); err == nil && size > 0 {
+		bi.maxBytes = size
+	}
+	if interval, err := time.ParseDuration(os.Getenv("ELASTIC_BULK_FLUSH_INTERVAL")); err == nil && interval > 0 {
+		bi.flushInterval = interval
+	}
+	go bi.flushPeriodically()
+	return bi
+}
+
+// flushPeriodically sends the queued documents every flushInterval and when
+// add signals enough of them are queued. It is the only sender of documents,
+// so a single bulk request is sent at a time.
+func (bi *bulkIndexer) flushPeriodically() {
+	defer close(bi.done)
+	ticker := time.NewTicker(bi.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			bi.flush()
+		case <-bi.flushNow:
+			bi.flush()
+		case <-bi.stop:
+			bi.flush()
+			return
+		}
+	}
+}
+
+// add queues the given document with the given timestamp, as Logstash would
+// have indexed it, and signals flushPeriodically if there are enough queued
+// documents. It never sends them itself, as it's called with the containers
+// locked.
+func (bi *bulkIndexer) add(doc interface{}, timestamp time.Time) error {
+	docBytes, err := withTimestamp(doc, timestamp)
+	if err != nil {
+		return err
+	}
+	req := elastic.NewBulkIndexRequest().
+		Index(bi.indexName + timestamp.UTC().Format(indexFormatString)).
+		Type(NodeTableName).
+		Doc(docBytes)
+	bi.mutex.Lock()
+	bi.pending = append(bi.pending, bulkDocument{req: req, size: len(docBytes)})
+	bi.pendingBytes += len(docBytes)
+	full := len(bi.pending) >= bi.maxActions || bi.pendingBytes >= bi.maxBytes
+	bi.mutex.Unlock()
+	if full {
+		select {
+		case bi.flushNow <- struct{}{}:
+		default:
+			// A flush is already signaled.
+		}
+	}
+	return nil
+}
+
+// flush sends the queued documents in requests of at most maxActions
+// documents and maxBytes. Documents rejected because ElasticSearch is
+// overloaded, or all of the remaining ones if it can't be reached, are queued
+// again.
+func (bi *bulkIndexer) flush() {
+	bi.mutex.Lock()
+	docs := bi.pending
+	bi.pending, bi.pendingBytes = nil, 0
+	bi.mutex.Unlock()
+	var retries []bulkDocument
+	for len(docs) != 0 {
+		n, size := 0, 0
+		for n < len(docs) && n < bi.maxActions && (n == 0 || size+docs[n].size <= bi.maxBytes) {
+			size += docs[n].size
+			n++
+		}
+		rejected, err := bi.send(docs[:n])
+		retries = append(retries, rejected...)
+		docs = docs[n:]
+		if err != nil {
+			retries = append(retries, docs...)
+			break
+		}
+	}
+	if len(retries) != 0 {
+		bi.requeue(retries)
+	}
+}
+
+// send sends the given documents in a single bulk request. It returns the
+// documents to send again, all of them if the request failed.
+func (bi *bulkIndexer) send(docs []bulkDocument) ([]bulkDocument, error) {
+	bulk := bi.client.Bulk()
+	for _, doc := range docs {
+		bulk.Add(doc.req)
+	}
+	res, err := bulk.Do()
+	if err != nil {
+		log.Error("Error while sending %d documents to elasticsearch: %s", len(docs), err)
+		return docs, err
+	}
+	var retries []bulkDocument
+	for i, item := range res.Items {
+		result, ok := item["index"]
+		if !ok || i >= len(docs) || (result.Status >= 200 && result.Status <= 299) {
+			continue
+		}
+		if result.Status == http.StatusTooManyRequests {
+			retries = append(retries, docs[i])
+			continue
+		}
+		if result.Error != nil {
+			log.Error("Error while indexing document in '%s': %s: %s", result.Index, result.Error.Type, result.Error.Reason)
+		} else {
+			log.Error("Error while indexing document in '%s': status %d", result.Index, result.Status)
+		}
+	}
+	if len(retries) != 0 {
+		log.Warning("%d documents rejected by elasticsearch, retrying on next flush", len(retries))
+	}
+	return retries, nil
+}
+
+// requeue queues again the given documents, before the ones added while they
+// were sent, dropping the oldest ones if there are too many.
+func (bi *bulkIndexer) requeue(docs []bulkDocument) {
+	bi.mutex.Lock()
+	defer bi.mutex.Unlock()
+	bi.pending = append(docs, bi.pending...)
+	for _, doc := range docs {
+		bi.pendingBytes += doc.size
+	}
+	dropped := 0
+	for len(bi.pending) > bi.maxActions*bulkMaxPendingFactor ||
+		bi.pendingBytes > bi.maxBytes*bulkMaxPendingFactor {
+		bi.pendingBytes -= bi.pending[0].size
+		bi.pending = bi.pending[1:]
+		dropped++
+	}
+	if dropped != 0 {
+		log.Error("Dropping %d documents not sent to elasticsearch", dropped)
+	}
+}
+
+// Close sends the queued documents and stops the periodic flushes.
+func (bi *bulkIndexer) Close() {
+	close(bi.stop)
+	<-bi.done
+}
+
+// withTimestamp returns the JSON encoding of the given document, which must be
+// a struct or a map, with the @timestamp field set to the given timestamp.
+func withTimestamp(doc interface{}, timestamp time.Time) (json.RawMessage, error) {
+	docBytes, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	tsBytes, err := json.Marshal(timestamp.UTC().Format(timestampFormat))
+	if err != nil {
+		return nil, err
+	}
+	buf := make([]byte, 0, len(docBytes)+len(timestampField)+len(tsBytes)+4)
+	buf = append(buf, `{"`+timestampField+`":`...)
+	buf = append(buf, tsBytes...)
+	if len(docBytes) > 2 {
+		buf = append(buf, ',')
+	}
+	buf = append(buf, docBytes[1:]...)
+	return buf, nil
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/gopkg.in/olivere/elastic.v3"
+)
+
+func TestWithTimestamp(t *testing.T) {
+	ts := time.Date(2016, 1, 2, 3, 4, 5, 6000000, time.FixedZone("", 3600))
+	got, err := withTimestamp(ENode{Family: nodeFamily, NodeName: "node1"}, ts)
+	if err != nil {
+		t.Fatalf("error while adding timestamp: %s", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(got, &doc); err != nil {
+		t.Fatalf("invalid JSON %s: %s", got, err)
+	}
+	if want := "2016-01-02T02:04:05.006Z"; doc[timestampField] != want {
+		t.Errorf("invalid timestamp:\ngot %v\nwant %s", doc[timestampField], want)
+	}
+	if doc["NodeName"] != "node1" {
+		t.Errorf("invalid NodeName:\ngot %v\nwant %s", doc["NodeName"], "node1")
+	}
+	got, err = withTimestamp(struct{}{}, ts)
+	if err != nil {
+		t.Fatalf("error while adding timestamp: %s", err)
+	}
+	if want := `{"@timestamp":"2016-01-02T02:04:05.006Z"}`; string(got) != want {
+		t.Errorf("invalid document:\ngot %s\nwant %s", got, want)
+	}
+}
+
+func TestBulkIndexerFlush(t *testing.T) {
+	var indices []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		indices = nil
+		scanner := bufio.NewScanner(r.Body)
+		for i := 0; scanner.Scan(); i++ {
+			if i%2 != 0 {
+				continue
+			}
+			var action map[string]map[string]string
+			json.Unmarshal(scanner.Bytes(), &action)
+			indices = append(indices, action["index"]["_index"])
+		}
+		w.Write([]byte(`{"took":1,"errors":true,"items":[
+			{"index":{"_index":"dc-2016-01-02","_type":"node_stats","status":201}},
+			{"index":{"_index":"dc-2016-01-02","_type":"node_stats","status":429}},
+			{"index":{"_index":"dc-2016-01-02","_type":"node_stats","status":400,
+				"error":{"type":"mapper_parsing_exception","reason":"failed to parse"}}}]}`))
+	}))
+	defer server.Close()
+	client, err := elastic.NewClient(elastic.SetURL(server.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	if err != nil {
+		t.Fatalf("error while creating client: %s", err)
+	}
+	bi := &bulkIndexer{client: client, indexName: "dc", maxActions: 3, maxBytes: 1 << 20, flushNow: make(chan struct{}, 1)}
+	ts := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := bi.add(ENode{NodeName: "node1"}, ts); err != nil {
+			t.Fatalf("error while adding document: %s", err)
+		}
+	}
+	// Documents are only sent by the flushes.
+	if indices != nil {
+		t.Errorf("documents sent while adding:\ngot %v", indices)
+	}
+	select {
+	case <-bi.flushNow:
+	default:
+		t.Fatalf("flush not signaled with %d documents", bi.maxActions)
+	}
+	bi.flush()
+	if len(indices) != 3 || indices[0] != "dc-2016-01-02" {
+		t.Errorf("invalid indices:\ngot %v\nwant 3 times %s", indices, "dc-2016-01-02")
+	}
+	if len(bi.pending) != 1 {
+		t.Errorf("invalid number of documents to retry:\ngot %d\nwant %d", len(bi.pending), 1)
+	}
+}
+
+func TestBulkIndexerAddWhileFlushing(t *testing.T) {
+	received := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- struct{}{}
+		<-release
+		w.Write([]byte(`{"took":1,"errors":true,"items":[
+			{"index":{"_index":"dc-2016-01-02","_type":"node_stats","status":429}}]}`))
+	}))
+	defer server.Close()
+	client, err := elastic.NewClient(elastic.SetURL(server.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	if err != nil {
+		t.Fatalf("error while creating client: %s", err)
+	}
+	bi := &bulkIndexer{client: client, indexName: "dc", maxActions: 10, maxBytes: 1 << 20}
+	ts := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := bi.add(ENode{NodeName: "node1"}, ts); err != nil {
+		t.Fatalf("error while adding document: %s", err)
+	}
+	flushed := make(chan struct{})
+	go func() {
+		bi.flush()
+		close(flushed)
+	}()
+	<-received
+	// Documents are added while the others are sent.
+	added := make(chan error)
+	go func() { added <- bi.add(ENode{NodeName: "node2"}, ts) }()
+	select {
+	case err := <-added:
+		if err != nil {
+			t.Fatalf("error while adding document: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("document not added while flushing")
+	}
+	close(release)
+	<-flushed
+	if len(bi.pending) != 2 {
+		t.Fatalf("invalid number of pending documents:\ngot %d\nwant %d", len(bi.pending), 2)
+	}
+	// The rejected document is sent before the one added while flushing.
+	for i, node := range []string{"node1", "node2"} {
+		source, err := bi.pending[i].req.Source()
+		if err != nil || len(source) != 2 || !strings.Contains(source[1], node) {
+			t.Errorf("invalid pending document %d:\ngot %v\nwant %s", i, source, node)
+		}
+	}
+}
+
+func TestBulkIndexerSize(t *testing.T) {
+	var requests []int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scanner := bufio.NewScanner(r.Body)
+		lines := 0
+		for scanner.Scan() {
+			lines++
+		}
+		requests = append(requests, lines/2)
+		w.Write([]byte(`{"took":1,"errors":false,"items":[]}`))
+	}))
+	defer server.Close()
+	client, err := elastic.NewClient(elastic.SetURL(server.URL), elastic.SetSniff(false), elastic.SetHealthcheck(false))
+	if err != nil {
+		t.Fatalf("error while creating client: %s", err)
+	}
+	ts := time.Date(2016, 1, 2, 3, 4, 5, 0, time.UTC)
+	doc, err := withTimestamp(ENode{NodeName: "node1"}, ts)
+	if err != nil {
+		t.Fatalf("error while adding timestamp: %s", err)
+	}
+	// Requests of at most 2 documents.
+	bi := &bulkIndexer{client: client, indexName: "dc", maxActions: 10, maxBytes: 2*len(doc) + 1, flushNow: make(chan struct{}, 1)}
+	for i := 0; i < 5; i++ {
+		if err := bi.add(ENode{NodeName: "node1"}, ts); err != nil {
+			t.Fatalf("error while adding document: %s", err)
+		}
+		if i == 1 && len(bi.flushNow) != 0 {
+			t.Errorf("flush signaled with %d bytes of documents", bi.pendingBytes)
+		}
+	}
+	if len(bi.flushNow) != 1 {
+		t.Errorf("flush not signaled with %d bytes of documents", bi.pendingBytes)
+	}
+	bi.flush()
+	if len(requests) != 3 || requests[0] != 2 || requests[1] != 2 || requests[2] != 1 {
+		t.Errorf("invalid documents per request:\ngot %v\nwant %v", requests, []int{2, 2, 1})
+	}
+	if len(bi.pending) != 0 || bi.pendingBytes != 0 {
+		t.Errorf("invalid pending documents:\ngot %d of %d bytes\nwant none", len(bi.pending), bi.pendingBytes)
+	}
+}
@@ -0,0 +1,283 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+const (
+	graphiteDefaultAddr     = "127.0.0.1:2003"
+	graphiteDefaultTemplate = "{prefix}.{node}.{container}.{interface}.{stat}"
+	graphitePlaintext       = "plaintext"
+	graphitePickle          = "pickle"
+	graphiteTimeout         = 5 * time.Second
+	graphiteMinBackoff      = time.Second
+	graphiteMaxBackoff      = time.Minute
+	// graphiteMaxBuffered is the number of metrics kept while Graphite can't
+	// be reached, the oldest ones are dropped first.
+	graphiteMaxBuffered = 100000
+	// graphitePickleBatch is the number of metrics per pickle message.
+	graphitePickleBatch = 500
+)
+
+var graphiteInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_:-]`)
+
+type graphiteMetric struct {
+	path      string
+	value     int64
+	timestamp int64
+}
+
+// GraphiteConn sends the samples of each tick to Graphite over TCP, in the
+// plaintext or the pickle protocol. Metrics are buffered while Graphite can't
+// be reached and the reconnections are spaced out exponentially.
+type GraphiteConn struct {
+	addr      string
+	protocol  string
+	prefix    string
+	template  string
+	conn      net.Conn
+	buffered  []graphiteMetric
+	backoff   time.Duration
+	nextRetry time.Time
+}
+
+// NewGraphiteConn returns a connection to the Graphite set in the environment:
+// GRAPHITE_ADDR, HOST:PORT (default "127.0.0.1:2003"), GRAPHITE_PROTOCOL,
+// plaintext (default) or pickle, and GRAPHITE_TEMPLATE, the metric path
+// template (default "{prefix}.{node}.{container}.{interface}.{stat}").
+func NewGraphiteConn(indexName string) (*GraphiteConn, error) {
+	log.Debug("")
+	addr := os.Getenv("GRAPHITE_ADDR")
+	if addr == "" {
+		addr = graphiteDefaultAddr
+	}
+	protocol := os.Getenv("GRAPHITE_PROTOCOL")
+	if protocol == "" {
+		protocol = graphitePlaintext
+	}
+	template := os.Getenv("GRAPHITE_TEMPLATE")
+	if template == "" {
+		template = graphiteDefaultTemplate
+	}
+	if indexName == "" {
+		indexName = elasticDefaultIndex
+	}
+	return NewGraphiteConnTo(addr, protocol, indexName, template)
+}
+
+// NewGraphiteConnTo returns a connection to the Graphite at the given address.
+// The {prefix}, {node}, {container}, {interface}, {family} and {stat}
+// placeholders of template are replaced in each metric path and the empty
+// path components removed. {stat} is the stat name, preceded by its family and
+// the values of its labels for samples other than network ones.
+func NewGraphiteConnTo(addr, protocol, prefix, template string) (*GraphiteConn, error) {
+	log.Debug("")
+	if protocol != graphitePlaintext && protocol != graphitePickle {
+		return nil, fmt.Errorf("unsupported Graphite protocol %q", protocol)
+	}
+	gc := &GraphiteConn{
+		addr:     addr,
+		protocol: protocol,
+		prefix:   prefix,
+		template: template,
+	}
+	// A Graphite that can't be reached yet isn't fatal, the metrics are
+	// buffered until it can.
+	if err := gc.connect(); err != nil {
+		log.Warning("Unable to connect to graphite: %s", err)
+	}
+	return gc, nil
+}
+
+func (gc *GraphiteConn) connect() error {
+	conn, err := net.DialTimeout("tcp", gc.addr, graphiteTimeout)
+	if err != nil {
+		gc.backoff *= 2
+		if gc.backoff < graphiteMinBackoff {
+			gc.backoff = graphiteMinBackoff
+		} else if gc.backoff > graphiteMaxBackoff {
+			gc.backoff = graphiteMaxBackoff
+		}
+		gc.nextRetry = time.Now().Add(gc.backoff)
+		return err
+	}
+	gc.conn = conn
+	gc.backoff = 0
+	return nil
+}
+
+func (gc *GraphiteConn) disconnect() {
+	if gc.conn != nil {
+		gc.conn.Close()
+		gc.conn = nil
+	}
+}
+
+func (gc *GraphiteConn) Close() {
+	if len(gc.buffered) != 0 && gc.conn != nil {
+		gc.flush()
+	}
+	gc.disconnect()
+}
+
+func (gc *GraphiteConn) CreateNode(node *uc.Node) error {
+	return nil
+}
+
+func (gc *GraphiteConn) CreateCluster() error {
+	return nil
+}
+
+// UpdateNode sends the given samples and the number of containers of the node,
+// along with the metrics buffered while Graphite couldn't be reached.
+func (gc *GraphiteConn) UpdateNode(node *uc.Node, samples []uc.Sample) error {
+	ts := time.Now().Unix()
+	for _, sample := range samples {
+		gc.buffer(graphiteMetric{gc.path(node, sample), sample.Value, ts})
+	}
+	gc.buffer(graphiteMetric{gc.path(node, uc.Sample{Family: nodeFamily, Name: "containers"}), int64(len(node.Containers)), ts})
+	gc.buffer(graphiteMetric{gc.path(node, uc.Sample{Family: nodeFamily, Name: "active_containers"}), int64(node.ActiveContainers()), ts})
+	if gc.conn == nil {
+		if time.Now().Before(gc.nextRetry) {
+			return nil
+		}
+		if err := gc.connect(); err != nil {
+			return fmt.Errorf("unable to reconnect to graphite, %d metrics buffered, retrying in %s: %s", len(gc.buffered), gc.backoff, err)
+		}
+	}
+	return gc.flush()
+}
+
+func (gc *GraphiteConn) buffer(m graphiteMetric) {
+	gc.buffered = append(gc.buffered, m)
+	if len(gc.buffered) > graphiteMaxBuffered {
+		gc.buffered = gc.buffered[len(gc.buffered)-graphiteMaxBuffered:]
+	}
+}
+
+// flush sends the buffered metrics. On error the connection is closed and the
+// metrics not sent are kept for the next reconnection.
+func (gc *GraphiteConn) flush() error {
+	for len(gc.buffered) != 0 {
+		n := len(gc.buffered)
+		if gc.protocol == graphitePickle && n > graphitePickleBatch {
+			n = graphitePickleBatch
+		}
+		var msg []byte
+		if gc.protocol == graphitePickle {
+			msg = graphitePickleMessage(gc.buffered[:n])
+		} else {
+			msg = graphitePlaintextMessage(gc.buffered[:n])
+		}
+		gc.conn.SetWriteDeadline(time.Now().Add(graphiteTimeout))
+		if _, err := gc.conn.Write(msg); err != nil {
+			gc.disconnect()
+			gc.nextRetry = time.Now()
+			return fmt.Errorf("error while sending metrics to graphite, %d metrics buffered: %s", len(gc.buffered), err)
+		}
+		gc.buffered = gc.buffered[n:]
+	}
+	gc.buffered = nil
+	return nil
+}
+
+// path returns the Graphite path of the given sample.
+func (gc *GraphiteConn) path(node *uc.Node, sample uc.Sample) string {
+	nodeName := sampleNodeName(node, sample)
+	container := ""
+	if cont := sample.Container; cont != nil {
+		container = cont.Name
+	}
+	stat := []string{graphiteSanitize(sample.Name)}
+	if sample.Family != uc.NetworkFamily {
+		keys := make([]string, 0, len(sample.Labels))
+		for k := range sample.Labels {
+			if k != uc.InterfaceLabel {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		stat = []string{graphiteSanitize(sample.Family)}
+		for _, k := range keys {
+			stat = append(stat, graphiteSanitize(sample.Labels[k]))
+		}
+		stat = append(stat, graphiteSanitize(sample.Name))
+	}
+	path := strings.NewReplacer(
+		"{prefix}", gc.prefix,
+		"{node}", graphiteSanitize(nodeName),
+		"{container}", graphiteSanitize(container),
+		"{interface}", graphiteSanitize(sample.Labels[uc.InterfaceLabel]),
+		"{family}", graphiteSanitize(sample.Family),
+		"{stat}", strings.Join(stat, "."),
+	).Replace(gc.template)
+	var components []string
+	for _, c := range strings.Split(path, ".") {
+		if c != "" {
+			components = append(components, c)
+		}
+	}
+	return strings.Join(components, ".")
+}
+
+// graphiteSanitize returns the given name usable as a path component: without
+// the leading "/" of container names and with the dots and other special
+// characters replaced by underscores.
+func graphiteSanitize(name string) string {
+	return graphiteInvalidChars.ReplaceAllString(strings.TrimPrefix(name, "/"), "_")
+}
+
+func graphitePlaintextMessage(metrics []graphiteMetric) []byte {
+	var b bytes.Buffer
+	for _, m := range metrics {
+		b.WriteString(m.path + " " + strconv.FormatInt(m.value, 10) + " " + strconv.FormatInt(m.timestamp, 10) + "\n")
+	}
+	return b.Bytes()
+}
+
+// graphitePickleMessage returns the given metrics as a list of
+// (path, (timestamp, value)) tuples in the pickle protocol 2, preceded by its
+// length, as carbon expects them.
+func graphitePickleMessage(metrics []graphiteMetric) []byte {
+	var b bytes.Buffer
+	b.Write([]byte{0x80, 2}) // PROTO 2
+	b.WriteByte(']')         // EMPTY_LIST
+	b.WriteByte('(')         // MARK
+	for _, m := range metrics {
+		b.WriteByte('X') // BINUNICODE
+		binary.Write(&b, binary.LittleEndian, uint32(len(m.path)))
+		b.WriteString(m.path)
+		pickleInt(&b, m.timestamp)
+		pickleInt(&b, m.value)
+		b.WriteByte(0x86) // TUPLE2
+		b.WriteByte(0x86) // TUPLE2
+	}
+	b.WriteByte('e') // APPENDS
+	b.WriteByte('.') // STOP
+	msg := make([]byte, 4, 4+b.Len())
+	binary.BigEndian.PutUint32(msg, uint32(b.Len()))
+	return append(msg, b.Bytes()...)
+}
+
+// pickleInt writes the given integer in the pickle protocol 2.
+func pickleInt(b *bytes.Buffer, i int64) {
+	if i >= -1<<31 && i < 1<<31 {
+		b.WriteByte('J') // BININT
+		binary.Write(b, binary.LittleEndian, int32(i))
+		return
+	}
+	b.WriteByte(0x8a) // LONG1
+	b.WriteByte(8)
+	binary.Write(b, binary.LittleEndian, i)
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+func TestGraphitePath(t *testing.T) {
+	gc := &GraphiteConn{prefix: "dc", template: graphiteDefaultTemplate}
+	node, samples := testSamples()
+	tests := []struct {
+		sample uc.Sample
+		want   string
+	}{
+		{samples[0], "dc.node1.web.eth0.rx_bytes"},
+		{samples[2], "dc.node1.system.load1"},
+		{uc.Sample{Family: "blkio", Name: "read_bytes", Labels: map[string]string{uc.DeviceLabel: "sda"},
+			Container: &uc.Container{Name: "/netns/my.ns", NodeName: "node1"}}, "dc.node1.netns_my_ns.blkio.sda.read_bytes"},
+	}
+	for _, tt := range tests {
+		if got := gc.path(node, tt.sample); got != tt.want {
+			t.Errorf("invalid path:\ngot %s\nwant %s", got, tt.want)
+		}
+	}
+	gc.template = "servers.{node}.{family}.{container}.{stat}"
+	if got, want := gc.path(node, samples[0]), "servers.node1.network.web.rx_bytes"; got != want {
+		t.Errorf("invalid path:\ngot %s\nwant %s", got, want)
+	}
+}
+
+func TestGraphitePickleMessage(t *testing.T) {
+	got := graphitePickleMessage([]graphiteMetric{{"a.b", 5, 1450000000}, {"c", -1 << 40, 1 << 33}})
+	// pickle.dumps([("a.b", (1450000000, 5)), ("c", (8589934592, -1099511627776))], 2)
+	want, _ := hex.DecodeString("0000003680025d285803000000612e624a803e6d564a0500000086865801000000638a0800000000020000008a080000000000ffffff8686652e")
+	if !bytes.Equal(got, want) {
+		t.Errorf("invalid pickle message:\ngot %x\nwant %x", got, want)
+	}
+}
+
+func TestGraphiteConnReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error while listening: %s", err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	gc, err := NewGraphiteConnTo(addr, graphitePlaintext, "dc", graphiteDefaultTemplate)
+	if err != nil {
+		t.Fatalf("error while creating connection: %s", err)
+	}
+	defer gc.Close()
+	node, samples := testSamples()
+	// Still backing off, the metrics are only buffered.
+	if err := gc.UpdateNode(node, samples[:1]); err != nil {
+		t.Errorf("unexpected error while backing off: %s", err)
+	}
+	if gc.backoff != graphiteMinBackoff || len(gc.buffered) != 3 {
+		t.Errorf("invalid state:\ngot backoff %s and %d metrics\nwant %s and %d", gc.backoff, len(gc.buffered), graphiteMinBackoff, 3)
+	}
+
+	if ln, err = net.Listen("tcp", addr); err != nil {
+		t.Skipf("unable to listen again on %s: %s", addr, err)
+	}
+	defer ln.Close()
+	gc.nextRetry = time.Now()
+	if err := gc.UpdateNode(node, samples[2:]); err != nil {
+		t.Fatalf("error while updating node: %s", err)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatalf("error while accepting: %s", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var paths []string
+	scanner := bufio.NewScanner(conn)
+	for len(paths) < 6 && scanner.Scan() {
+		paths = append(paths, strings.Fields(scanner.Text())[0])
+	}
+	want := "dc.node1.web.eth0.rx_bytes dc.node1.node.containers dc.node1.node.active_containers " +
+		"dc.node1.system.load1 dc.node1.node.containers dc.node1.node.active_containers"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("invalid metrics:\ngot %s\nwant %s", got, want)
+	}
+	if gc.backoff != 0 || len(gc.buffered) != 0 {
+		t.Errorf("invalid state after reconnection: backoff %s, %d metrics buffered", gc.backoff, len(gc.buffered))
+	}
+}
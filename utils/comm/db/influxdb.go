@@ -0,0 +1,274 @@
+package db
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+const (
+	influxDefaultURL       = "http://127.0.0.1:8086"
+	influxDefaultBatchSize = 5000
+	// influxUDPPayloadSize is the maximum size of the UDP datagrams, lines
+	// are packed in datagrams up to this size.
+	influxUDPPayloadSize = 512
+	influxTimeout        = 10 * time.Second
+	// Tags set on every line, besides the labels of the samples.
+	influxNodeTag      = "node"
+	influxContainerTag = "container"
+	influxImageTag     = "image"
+)
+
+var (
+	influxMeasurementEscaper = strings.NewReplacer(",", `\,`, " ", `\ `)
+	influxKeyEscaper         = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+)
+
+// InfluxConn writes the samples of each tick to InfluxDB in the line protocol,
+// through the HTTP /write endpoint or over UDP.
+type InfluxConn struct {
+	url       *url.URL
+	database  string
+	username  string
+	password  string
+	batchSize int
+	client    *http.Client
+	udpConn   net.Conn
+}
+
+// NewInfluxConn returns a connection to the InfluxDB set in the environment:
+// INFLUXDB_URL, http://HOST:PORT or udp://HOST:PORT (default
+// "http://127.0.0.1:8086"), INFLUXDB_DB (default the index name),
+// INFLUXDB_USER, INFLUXDB_PASSWORD and INFLUXDB_BATCH_SIZE, the number of
+// lines per HTTP request.
+func NewInfluxConn(indexName string) (*InfluxConn, error) {
+	log.Debug("")
+	rawURL := os.Getenv("INFLUXDB_URL")
+	if rawURL == "" {
+		rawURL = influxDefaultURL
+	}
+	database := os.Getenv("INFLUXDB_DB")
+	if database == "" {
+		database = indexName
+	}
+	if database == "" {
+		database = elasticDefaultIndex
+	}
+	batchSize := influxDefaultBatchSize
+	if size, err := strconv.Atoi(os.Getenv("INFLUXDB_BATCH_SIZE")); err == nil && size > 0 {
+		batchSize = size
+	}
+	return NewInfluxConnTo(rawURL, database, os.Getenv("INFLUXDB_USER"), os.Getenv("INFLUXDB_PASSWORD"), batchSize)
+}
+
+// NewInfluxConnTo returns a connection to the InfluxDB at the given URL.
+func NewInfluxConnTo(rawURL, database, username, password string, batchSize int) (*InfluxConn, error) {
+	log.Debug("")
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	ic := &InfluxConn{
+		url:       u,
+		database:  database,
+		username:  username,
+		password:  password,
+		batchSize: batchSize,
+	}
+	switch u.Scheme {
+	case "http", "https":
+		ic.client = &http.Client{Timeout: influxTimeout}
+	case "udp":
+		if ic.udpConn, err = net.Dial("udp", u.Host); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported InfluxDB URL scheme %q", u.Scheme)
+	}
+	return ic, nil
+}
+
+// InitInfluxDb creates the database, unless samples are sent over UDP, in
+// which case the database is set in the InfluxDB configuration.
+func InitInfluxDb(indexName string) error {
+	ic, err := NewInfluxConn(indexName)
+	if err != nil {
+		return err
+	}
+	defer ic.Close()
+	return ic.CreateCluster()
+}
+
+func (ic *InfluxConn) Close() {
+	if ic.udpConn != nil {
+		ic.udpConn.Close()
+	}
+}
+
+// CreateCluster creates the database if it doesn't exist.
+func (ic *InfluxConn) CreateCluster() error {
+	if ic.client == nil {
+		return nil
+	}
+	params := url.Values{}
+	params.Set("q", `CREATE DATABASE "`+strings.Replace(ic.database, `"`, `\"`, -1)+`"`)
+	req, err := http.NewRequest("POST", ic.endpoint("/query", params), nil)
+	if err != nil {
+		return err
+	}
+	return ic.do(req)
+}
+
+func (ic *InfluxConn) CreateNode(node *uc.Node) error {
+	return nil
+}
+
+// UpdateNode writes a line per metric family and tag set, with the stat
+// values as fields, and a line with the number of containers of the node.
+// Counters are written with their absolute value.
+func (ic *InfluxConn) UpdateNode(node *uc.Node, samples []uc.Sample) error {
+	now := time.Now()
+	node.UpdatedAt = now
+	lines := influxLines(node, samples, now)
+	if ic.udpConn != nil {
+		return ic.writeUDP(lines)
+	}
+	for start := 0; start < len(lines); start += ic.batchSize {
+		end := start + ic.batchSize
+		if end > len(lines) {
+			end = len(lines)
+		}
+		if err := ic.writeHTTP(lines[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (ic *InfluxConn) endpoint(path string, params url.Values) string {
+	u := *ic.url
+	u.Path = strings.TrimSuffix(u.Path, "/") + path
+	u.RawQuery = params.Encode()
+	return u.String()
+}
+
+// writeHTTP sends the given lines, gzipped, in a single request.
+func (ic *InfluxConn) writeHTTP(lines []string) error {
+	var body bytes.Buffer
+	gz := gzip.NewWriter(&body)
+	for _, line := range lines {
+		gz.Write([]byte(line))
+		gz.Write([]byte{'\n'})
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	params := url.Values{}
+	params.Set("db", ic.database)
+	params.Set("precision", "ns")
+	req, err := http.NewRequest("POST", ic.endpoint("/write", params), &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Content-Encoding", "gzip")
+	return ic.do(req)
+}
+
+func (ic *InfluxConn) do(req *http.Request) error {
+	if ic.username != "" {
+		req.SetBasicAuth(ic.username, ic.password)
+	}
+	res, err := ic.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(res.Body)
+		return fmt.Errorf("influxdb returned %s: %s", res.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// writeUDP sends the given lines packed in datagrams of at most
+// influxUDPPayloadSize bytes.
+func (ic *InfluxConn) writeUDP(lines []string) error {
+	return writePacked(ic.udpConn, lines, influxUDPPayloadSize)
+}
+
+type influxPoint struct {
+	measurement string
+	tags        string
+	fields      []string
+}
+
+// influxLines returns the line protocol of the given samples, one line per
+// metric family and tag set, and of the node.
+func influxLines(node *uc.Node, samples []uc.Sample, timestamp time.Time) []string {
+	ts := strconv.FormatInt(timestamp.UnixNano(), 10)
+	points := map[string]*influxPoint{}
+	var keys []string
+	for _, sample := range samples {
+		measurement := influxMeasurementEscaper.Replace(sample.Family)
+		tags := influxTags(node, sample)
+		key := measurement + tags
+		p, ok := points[key]
+		if !ok {
+			p = &influxPoint{measurement: measurement, tags: tags}
+			points[key] = p
+			keys = append(keys, key)
+		}
+		value := sample.Value
+		if sample.Kind == uc.Counter {
+			value = sample.ValueRead
+		}
+		p.fields = append(p.fields, influxKeyEscaper.Replace(sample.Name)+"="+strconv.FormatInt(value, 10)+"i")
+	}
+	lines := make([]string, 0, len(keys)+1)
+	for _, key := range keys {
+		p := points[key]
+		lines = append(lines, p.measurement+p.tags+" "+strings.Join(p.fields, ",")+" "+ts)
+	}
+	lines = append(lines, fmt.Sprintf("%s,%s=%s containers=%di,active_containers=%di %s",
+		nodeFamily, influxNodeTag, influxKeyEscaper.Replace(node.Name),
+		len(node.Containers), node.ActiveContainers(), ts))
+	return lines
+}
+
+// influxTags returns the tags of the given sample, sorted by key as InfluxDB
+// recommends, each preceded by a comma.
+func influxTags(node *uc.Node, sample uc.Sample) string {
+	tags := map[string]string{influxNodeTag: sampleNodeName(node, sample)}
+	for k, v := range sample.Labels {
+		tags[k] = v
+	}
+	if cont := sample.Container; cont != nil {
+		tags[influxContainerTag] = strings.TrimPrefix(cont.Name, "/")
+		tags[influxImageTag] = cont.Metadata.Image
+	}
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		// InfluxDB rejects empty tag values.
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	var b bytes.Buffer
+	for _, k := range keys {
+		b.WriteString("," + influxKeyEscaper.Replace(k) + "=" + influxKeyEscaper.Replace(tags[k]))
+	}
+	return b.String()
+}
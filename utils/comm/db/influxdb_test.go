@@ -0,0 +1,113 @@
+package db
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+func TestInfluxLines(t *testing.T) {
+	node, samples := testSamples()
+	got := influxLines(node, samples, time.Unix(1, 5))
+	want := []string{
+		"network,container=web,image=nginx:1.9,interface=eth0,node=node1 rx_bytes=1500i,tx_bytes=3000i 1000000005",
+		"system,node=node1 load1=42i 1000000005",
+		"node,node=node1 containers=1i,active_containers=1i 1000000005",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("invalid lines:\ngot\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	sample := uc.Sample{Family: "my family", Name: "a=b", Labels: map[string]string{"path": "a b,c", "empty": ""}}
+	got = influxLines(&uc.Node{Name: "node1"}, []uc.Sample{sample}, time.Unix(1, 0))
+	if want := `my\ family,node=node1,path=a\ b\,c a\=b=0i 1000000000`; got[0] != want {
+		t.Errorf("invalid escaping:\ngot %s\nwant %s", got[0], want)
+	}
+}
+
+func TestInfluxConnHTTP(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/query" {
+			if q := r.URL.Query().Get("q"); q != `CREATE DATABASE "dc"` {
+				t.Errorf("invalid query:\ngot %s\nwant %s", q, `CREATE DATABASE "dc"`)
+			}
+			return
+		}
+		if r.URL.Path != "/write" || r.URL.Query().Get("db") != "dc" {
+			t.Errorf("invalid write URL: %s", r.URL)
+		}
+		if u, p, _ := r.BasicAuth(); u != "user" || p != "pass" {
+			t.Errorf("invalid credentials:\ngot %s:%s\nwant %s", u, p, "user:pass")
+		}
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("body isn't gzipped: %s", err)
+		}
+		body, _ := ioutil.ReadAll(gz)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+	ic, err := NewInfluxConnTo(server.URL, "dc", "user", "pass", 2)
+	if err != nil {
+		t.Fatalf("error while connecting: %s", err)
+	}
+	if err := ic.CreateCluster(); err != nil {
+		t.Fatalf("error while creating database: %s", err)
+	}
+	node, samples := testSamples()
+	if err := ic.UpdateNode(node, samples); err != nil {
+		t.Fatalf("error while updating node: %s", err)
+	}
+	if len(bodies) != 2 || strings.Count(bodies[0], "\n") != 2 || strings.Count(bodies[1], "\n") != 1 {
+		t.Errorf("invalid batches:\ngot %q\nwant 2 and 1 lines", bodies)
+	}
+}
+
+func TestInfluxConnHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"database not found: \"dc\""}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+	ic, err := NewInfluxConnTo(server.URL, "dc", "", "", 10)
+	if err != nil {
+		t.Fatalf("error while connecting: %s", err)
+	}
+	node, samples := testSamples()
+	if err := ic.UpdateNode(node, samples); err == nil || !strings.Contains(err.Error(), "database not found") {
+		t.Errorf("invalid error:\ngot %v\nwant %s", err, "database not found")
+	}
+}
+
+func TestInfluxConnUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error while listening: %s", err)
+	}
+	defer pc.Close()
+	ic, err := NewInfluxConnTo("udp://"+pc.LocalAddr().String(), "dc", "", "", 10)
+	if err != nil {
+		t.Fatalf("error while connecting: %s", err)
+	}
+	defer ic.Close()
+	node, samples := testSamples()
+	if err := ic.UpdateNode(node, samples); err != nil {
+		t.Fatalf("error while updating node: %s", err)
+	}
+	pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+	buf := make([]byte, influxUDPPayloadSize)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("error while reading datagram: %s", err)
+	}
+	if lines := strings.Count(string(buf[:n]), "\n"); lines != 3 {
+		t.Errorf("invalid number of lines in datagram:\ngot %d\nwant %d", lines, 3)
+	}
+}
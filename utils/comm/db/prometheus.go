@@ -0,0 +1,188 @@
+package db
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+const (
+	prometheusDefaultAddr = ":9280"
+	prometheusPath        = "/metrics"
+	// ContainerLabel and NodeLabel are the Prometheus labels of the container
+	// name and of the node name of every metric.
+	ContainerLabel = "container"
+	NodeLabel      = "node"
+)
+
+var promInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+type promMetric struct {
+	name   string
+	kind   uc.MetricKind
+	labels map[string]string
+	value  int64
+}
+
+// PromConn exposes the last samples of every container and of the node in the
+// Prometheus text format on an HTTP endpoint.
+type PromConn struct {
+	listener net.Listener
+	mutex    sync.RWMutex
+	metrics  []promMetric
+}
+
+// NewPromConn returns a connection serving /metrics on the address set in the
+// PROMETHEUS_ADDR environment variable, or on port 9280 of all interfaces.
+func NewPromConn() (*PromConn, error) {
+	log.Debug("")
+	addr := os.Getenv("PROMETHEUS_ADDR")
+	if addr == "" {
+		addr = prometheusDefaultAddr
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	pc := &PromConn{listener: listener}
+	mux := http.NewServeMux()
+	mux.Handle(prometheusPath, pc)
+	go func() {
+		if err := http.Serve(listener, mux); err != nil {
+			log.Debug("Prometheus endpoint closed: %s", err)
+		}
+	}()
+	log.Info("Serving Prometheus metrics on %s%s", listener.Addr(), prometheusPath)
+	return pc, nil
+}
+
+func (pc *PromConn) Close() {
+	pc.listener.Close()
+}
+
+// UpdateNode replaces the exposed metrics by the given samples. Counters are
+// exposed with their absolute value, as Prometheus computes the rates itself.
+// Calls without samples, on container events, keep the last samples.
+func (pc *PromConn) UpdateNode(node *uc.Node, samples []uc.Sample) error {
+	if len(samples) == 0 {
+		return nil
+	}
+	metrics := make([]promMetric, 0, len(samples))
+	for _, sample := range samples {
+		metrics = append(metrics, convertToPromMetric(node, sample))
+	}
+	sort.Sort(byPromName(metrics))
+	pc.mutex.Lock()
+	pc.metrics = metrics
+	pc.mutex.Unlock()
+	return nil
+}
+
+func (pc *PromConn) CreateNode(node *uc.Node) error {
+	return nil
+}
+
+func (pc *PromConn) CreateCluster() error {
+	return nil
+}
+
+func (pc *PromConn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pc.mutex.RLock()
+	defer pc.mutex.RUnlock()
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write(writePromMetrics(pc.metrics))
+}
+
+func convertToPromMetric(node *uc.Node, sample uc.Sample) promMetric {
+	pm := promMetric{
+		kind:   sample.Kind,
+		labels: map[string]string{NodeLabel: sampleNodeName(node, sample)},
+		value:  sample.Value,
+	}
+	prefix := "node_"
+	if cont := sample.Container; cont != nil {
+		prefix = "container_"
+		pm.labels[ContainerLabel] = strings.TrimPrefix(cont.Name, "/")
+	}
+	for k, v := range sample.Labels {
+		pm.labels[promName(k)] = v
+	}
+	pm.name = prefix + promName(sample.Family) + "_" + promStatName(sample.Name)
+	if sample.Kind == uc.Counter {
+		pm.name += "_total"
+		pm.value = sample.ValueRead
+	}
+	return pm
+}
+
+// promStatName returns the Prometheus name of a statistic, with the rx_ and
+// tx_ prefixes of network statistics spelled out.
+func promStatName(name string) string {
+	switch {
+	case strings.HasPrefix(name, "rx_"):
+		name = "receive_" + name[3:]
+	case strings.HasPrefix(name, "tx_"):
+		name = "transmit_" + name[3:]
+	}
+	return promName(name)
+}
+
+// promName replaces the characters not allowed in Prometheus metric and label
+// names by underscores.
+func promName(name string) string {
+	return promInvalidChars.ReplaceAllString(name, "_")
+}
+
+type byPromName []promMetric
+
+func (m byPromName) Len() int           { return len(m) }
+func (m byPromName) Swap(i, j int)      { m[i], m[j] = m[j], m[i] }
+func (m byPromName) Less(i, j int) bool { return m[i].name < m[j].name }
+
+// writePromMetrics returns the given metrics, sorted by name, in the
+// Prometheus text exposition format.
+func writePromMetrics(metrics []promMetric) []byte {
+	var b bytes.Buffer
+	for i, m := range metrics {
+		if i == 0 || metrics[i-1].name != m.name {
+			kind := "counter"
+			if m.kind == uc.Gauge {
+				kind = "gauge"
+			}
+			b.WriteString("# TYPE " + m.name + " " + kind + "\n")
+		}
+		b.WriteString(m.name)
+		keys := make([]string, 0, len(m.labels))
+		for k := range m.labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for j, k := range keys {
+			if j == 0 {
+				b.WriteByte('{')
+			} else {
+				b.WriteByte(',')
+			}
+			b.WriteString(k + `="` + escapePromLabel(m.labels[k]) + `"`)
+		}
+		if len(keys) != 0 {
+			b.WriteByte('}')
+		}
+		b.WriteString(" " + strconv.FormatInt(m.value, 10) + "\n")
+	}
+	return b.Bytes()
+}
+
+var promLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapePromLabel(value string) string {
+	return promLabelReplacer.Replace(value)
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+func TestPromConnServeHTTP(t *testing.T) {
+	node := &uc.Node{Name: "node1"}
+	cont := &uc.Container{Name: "/web", NodeName: "node1"}
+	samples := []uc.Sample{
+		{
+			Family:    uc.NetworkFamily,
+			Name:      "rx_bytes",
+			Kind:      uc.Counter,
+			Labels:    map[string]string{"interface": "eth0"},
+			Value:     10,
+			ValueRead: 1500,
+			Container: cont,
+		},
+		{
+			Family: "system",
+			Name:   "load1",
+			Kind:   uc.Gauge,
+			Value:  42,
+		},
+		{
+			Family:    "memory",
+			Name:      "usage",
+			Kind:      uc.Gauge,
+			Labels:    map[string]string{"path": `a"b`},
+			Value:     7,
+			Container: cont,
+		},
+	}
+	pc := &PromConn{}
+	if err := pc.UpdateNode(node, samples); err != nil {
+		t.Fatalf("error while updating node: %s", err)
+	}
+	// Updates without samples keep the last ones.
+	if err := pc.UpdateNode(node, nil); err != nil {
+		t.Fatalf("error while updating node: %s", err)
+	}
+	w := httptest.NewRecorder()
+	pc.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	want := `# TYPE container_memory_usage gauge
+container_memory_usage{container="web",node="node1",path="a\"b"} 7
+# TYPE container_network_receive_bytes_total counter
+container_network_receive_bytes_total{container="web",interface="eth0",node="node1"} 1500
+# TYPE node_system_load1 gauge
+node_system_load1{node="node1"} 42
+`
+	if got := w.Body.String(); got != want {
+		t.Errorf("invalid metrics:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,179 @@
+package db
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+const (
+	statsdDefaultAddr     = "udp://127.0.0.1:8125"
+	statsdDefaultTemplate = "{prefix}.{family}.{name}"
+	// statsdDefaultMTU is the payload of a UDP datagram on a 1500 bytes MTU
+	// network, as recommended by DogStatsD.
+	statsdDefaultMTU = 1432
+)
+
+var (
+	statsdInvalidNameChars = regexp.MustCompile(`[:|@#,\s]`)
+	statsdInvalidTagChars  = regexp.MustCompile(`[|#,\s]`)
+)
+
+// StatsdConn sends the samples of each tick to a StatsD agent: deltas of
+// counters as counters and the other metrics as gauges, with DogStatsD tags.
+type StatsdConn struct {
+	conn     net.Conn
+	prefix   string
+	template string
+	tags     bool
+	mtu      int
+}
+
+// NewStatsdConn returns a connection to the StatsD agent set in the
+// environment: STATSD_ADDR, udp://HOST:PORT or unixgram://PATH (default
+// "udp://127.0.0.1:8125"), STATSD_TEMPLATE, the metric name template (default
+// "{prefix}.{family}.{name}"), STATSD_TAGS, false to disable the DogStatsD
+// tags, and STATSD_MTU, the maximum size of each packet (default 1432).
+func NewStatsdConn(indexName string) (*StatsdConn, error) {
+	log.Debug("")
+	addr := os.Getenv("STATSD_ADDR")
+	if addr == "" {
+		addr = statsdDefaultAddr
+	}
+	template := os.Getenv("STATSD_TEMPLATE")
+	if template == "" {
+		template = statsdDefaultTemplate
+	}
+	if indexName == "" {
+		indexName = elasticDefaultIndex
+	}
+	tags := true
+	if t, err := strconv.ParseBool(os.Getenv("STATSD_TAGS")); err == nil {
+		tags = t
+	}
+	mtu := statsdDefaultMTU
+	if m, err := strconv.Atoi(os.Getenv("STATSD_MTU")); err == nil && m > 0 {
+		mtu = m
+	}
+	return NewStatsdConnTo(addr, indexName, template, tags, mtu)
+}
+
+// NewStatsdConnTo returns a connection to the StatsD agent at the given
+// address. The {prefix}, {family}, {name}, {node}, {container} and {interface}
+// placeholders of template are replaced in each metric name.
+func NewStatsdConnTo(addr, prefix, template string, tags bool, mtu int) (*StatsdConn, error) {
+	log.Debug("")
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	sc := &StatsdConn{
+		prefix:   prefix,
+		template: template,
+		tags:     tags,
+		mtu:      mtu,
+	}
+	switch u.Scheme {
+	case "udp":
+		sc.conn, err = net.Dial("udp", u.Host)
+	case "unixgram":
+		sc.conn, err = net.Dial("unixgram", u.Path)
+	default:
+		return nil, fmt.Errorf("unsupported StatsD address scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+func (sc *StatsdConn) Close() {
+	sc.conn.Close()
+}
+
+func (sc *StatsdConn) CreateNode(node *uc.Node) error {
+	return nil
+}
+
+func (sc *StatsdConn) CreateCluster() error {
+	return nil
+}
+
+// UpdateNode sends the given samples and the number of containers of the node,
+// packed in packets of at most mtu bytes. Negative deltas of counters, left
+// by counters reset such as when an interface is recreated, aren't sent since
+// StatsD adds up the deltas it receives.
+func (sc *StatsdConn) UpdateNode(node *uc.Node, samples []uc.Sample) error {
+	lines := make([]string, 0, len(samples)+2)
+	for _, sample := range samples {
+		if sample.Kind == uc.Counter && sample.Value < 0 {
+			continue
+		}
+		lines = append(lines, sc.line(node, sample))
+	}
+	lines = append(lines,
+		sc.line(node, uc.Sample{Family: nodeFamily, Name: "containers", Kind: uc.Gauge, Value: int64(len(node.Containers))}),
+		sc.line(node, uc.Sample{Family: nodeFamily, Name: "active_containers", Kind: uc.Gauge, Value: int64(node.ActiveContainers())}),
+	)
+	return writePacked(sc.conn, lines, sc.mtu)
+}
+
+// line returns the StatsD line of the given sample.
+func (sc *StatsdConn) line(node *uc.Node, sample uc.Sample) string {
+	tags := map[string]string{NodeLabel: sampleNodeName(node, sample)}
+	for k, v := range sample.Labels {
+		tags[k] = v
+	}
+	if cont := sample.Container; cont != nil {
+		tags[ContainerLabel] = strings.TrimPrefix(cont.Name, "/")
+	}
+	name := strings.NewReplacer(
+		"{prefix}", sc.prefix,
+		"{family}", sample.Family,
+		"{name}", sample.Name,
+		"{node}", tags[NodeLabel],
+		"{container}", tags[ContainerLabel],
+		"{interface}", tags[uc.InterfaceLabel],
+	).Replace(sc.template)
+	// Placeholders without value, such as the container of node metrics,
+	// leave empty components.
+	var components []string
+	for _, c := range strings.Split(name, ".") {
+		if c != "" {
+			components = append(components, c)
+		}
+	}
+	name = strings.Join(components, ".")
+	line := statsdInvalidNameChars.ReplaceAllString(name, "_") + ":" + strconv.FormatInt(sample.Value, 10)
+	if sample.Kind == uc.Counter {
+		line += "|c"
+	} else {
+		line += "|g"
+	}
+	if !sc.tags {
+		return line
+	}
+	keys := make([]string, 0, len(tags))
+	for k, v := range tags {
+		if v != "" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		if i == 0 {
+			line += "|#"
+		} else {
+			line += ","
+		}
+		line += statsdInvalidTagChars.ReplaceAllString(k, "_") + ":" + statsdInvalidTagChars.ReplaceAllString(tags[k], "_")
+	}
+	return line
+}
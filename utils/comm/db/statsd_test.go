@@ -0,0 +1,114 @@
+package db
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	uc "github.com/cilium-team/docker-collector/utils/comm"
+)
+
+func readPackets(t *testing.T, pc net.PacketConn, n int) []string {
+	var packets []string
+	buf := make([]byte, 65536)
+	for i := 0; i < n; i++ {
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		size, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("error while reading packet %d: %s", i, err)
+		}
+		packets = append(packets, string(buf[:size]))
+	}
+	return packets
+}
+
+func TestStatsdConnUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error while listening: %s", err)
+	}
+	defer pc.Close()
+	sc, err := NewStatsdConnTo("udp://"+pc.LocalAddr().String(), "dc", statsdDefaultTemplate, true, 1432)
+	if err != nil {
+		t.Fatalf("error while connecting: %s", err)
+	}
+	defer sc.Close()
+	node, samples := testSamples()
+	if err := sc.UpdateNode(node, samples); err != nil {
+		t.Fatalf("error while updating node: %s", err)
+	}
+	got := readPackets(t, pc, 1)[0]
+	want := "dc.network.rx_bytes:10|c|#container:web,interface:eth0,node:node1\n" +
+		"dc.network.tx_bytes:20|c|#container:web,interface:eth0,node:node1\n" +
+		"dc.system.load1:42|g|#node:node1\n" +
+		"dc.node.containers:1|g|#node:node1\n" +
+		"dc.node.active_containers:1|g|#node:node1\n"
+	if got != want {
+		t.Errorf("invalid packet:\ngot\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestStatsdConnTemplateAndMTU(t *testing.T) {
+	dir, err := ioutil.TempDir("", "statsd")
+	if err != nil {
+		t.Fatalf("error while creating directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "statsd.sock")
+	pc, err := net.ListenPacket("unixgram", path)
+	if err != nil {
+		t.Fatalf("error while listening: %s", err)
+	}
+	defer pc.Close()
+	sc, err := NewStatsdConnTo("unixgram://"+path, "dc", "{node}.{container}.{interface}.{name}", false, 70)
+	if err != nil {
+		t.Fatalf("error while connecting: %s", err)
+	}
+	defer sc.Close()
+	node, samples := testSamples()
+	if err := sc.UpdateNode(node, samples[:2]); err != nil {
+		t.Fatalf("error while updating node: %s", err)
+	}
+	packets := readPackets(t, pc, 2)
+	if want := "node1.web.eth0.rx_bytes:10|c\nnode1.web.eth0.tx_bytes:20|c\n"; packets[0] != want {
+		t.Errorf("invalid first packet:\ngot\n%s\nwant\n%s", packets[0], want)
+	}
+	for _, packet := range packets {
+		if len(packet) > 70 {
+			t.Errorf("packet larger than the MTU: %d bytes", len(packet))
+		}
+	}
+	// Empty components, such as the container and the interface of node
+	// metrics, are dropped.
+	if want := "node1.containers:1|g\nnode1.active_containers:1|g\n"; packets[1] != want {
+		t.Errorf("invalid node metrics:\ngot\n%s\nwant\n%s", packets[1], want)
+	}
+}
+
+func TestStatsdConnCounterReset(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("error while listening: %s", err)
+	}
+	defer pc.Close()
+	sc, err := NewStatsdConnTo("udp://"+pc.LocalAddr().String(), "dc", "{prefix}.{container}.{name}", false, 1432)
+	if err != nil {
+		t.Fatalf("error while connecting: %s", err)
+	}
+	defer sc.Close()
+	node, samples := testSamples()
+	// The counter of rx_bytes was reset since the last read.
+	samples[0].Value = -1490
+	gauge := uc.Sample{Family: "memory", Name: "usage", Kind: uc.Gauge, Value: -1, Container: &node.Containers[0]}
+	if err := sc.UpdateNode(node, append(samples, gauge)); err != nil {
+		t.Fatalf("error while updating node: %s", err)
+	}
+	packets := readPackets(t, pc, 1)
+	want := "dc.web.tx_bytes:20|c\ndc.load1:42|g\ndc.web.usage:-1|g\ndc.containers:1|g\ndc.active_containers:1|g\n"
+	if packets[0] != want {
+		t.Errorf("invalid packet:\ngot\n%s\nwant\n%s", packets[0], want)
+	}
+}
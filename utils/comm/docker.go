@@ -3,6 +3,8 @@ package comm
 import (
 	"fmt"
 	"os"
+	"strings"
+	"sync"
 
 	d "github.com/cilium-team/docker-collector/Godeps/_workspace/src/github.com/fsouza/go-dockerclient"
 	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/github.com/op/go-logging"
@@ -13,17 +15,29 @@ var log = logging.MustGetLogger("docker-collector")
 
 const (
 	defaultEndpoint = "unix:///var/run/docker.sock"
+	// DockerRuntimeName is the name of the Docker Engine runtime.
+	DockerRuntimeName = "docker"
 )
 
 type Docker struct {
 	*d.Client
 }
 
-func NewDockerClient() (cli Docker, err error) {
+// dockerEndpoint returns the endpoint of the Docker daemon, set in the
+// DOCKER_HOST environment variable or the default one.
+func dockerEndpoint() string {
 	endpoint := os.Getenv("DOCKER_HOST")
 	if endpoint == "" {
 		endpoint = defaultEndpoint
 	}
+	return endpoint
+}
+
+func NewDockerClient() (cli Docker, err error) {
+	return newDockerClientAt(dockerEndpoint())
+}
+
+func newDockerClientAt(endpoint string) (cli Docker, err error) {
 	path := os.Getenv("DOCKER_CERT_PATH")
 	if path != "" {
 		ca := fmt.Sprintf("%s/ca.pem", path)
@@ -37,10 +51,10 @@ func NewDockerClient() (cli Docker, err error) {
 }
 
 func NewDockerClientSamalba() (cli *dsamalba.DockerClient, err error) {
-	endpoint := os.Getenv("DOCKER_HOST")
-	if endpoint == "" {
-		endpoint = defaultEndpoint
-	}
+	return newDockerClientSamalbaAt(dockerEndpoint())
+}
+
+func newDockerClientSamalbaAt(endpoint string) (cli *dsamalba.DockerClient, err error) {
 	path := os.Getenv("DOCKER_CERT_PATH")
 	if path != "" {
 		//		ca := fmt.Sprintf("%s/ca.pem", path)
@@ -53,3 +67,135 @@ func NewDockerClientSamalba() (cli *dsamalba.DockerClient, err error) {
 	}
 	return
 }
+
+// DockerRuntime is the Docker Engine runtime. Events are monitored with the
+// samalba client while everything else uses the fsouza client.
+type DockerRuntime struct {
+	client Docker
+	events *dsamalba.DockerClient
+	// imageDigests are the repository digests of the images by ID, as of
+	// the last listing of images.
+	imageDigests map[string][]string
+	mutex        sync.Mutex
+}
+
+func NewDockerRuntime() (*DockerRuntime, error) {
+	return newDockerRuntimeAt(dockerEndpoint())
+}
+
+func newDockerRuntimeAt(endpoint string) (*DockerRuntime, error) {
+	client, err := newDockerClientAt(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	events, err := newDockerClientSamalbaAt(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	return &DockerRuntime{client: client, events: events}, nil
+}
+
+func (dr *DockerRuntime) Name() string {
+	return DockerRuntimeName
+}
+
+func (dr *DockerRuntime) Ping() error {
+	_, err := dr.events.Info()
+	return err
+}
+
+func (dr *DockerRuntime) ListContainers() ([]RuntimeContainer, error) {
+	apiContainers, err := dr.events.ListContainers(true, false, "")
+	if err != nil {
+		return nil, err
+	}
+	containers := make([]RuntimeContainer, 0, len(apiContainers))
+	for _, apiContainer := range apiContainers {
+		rc := RuntimeContainer{ID: apiContainer.Id}
+		if len(apiContainer.Names) != 0 {
+			rc.Name = apiContainer.Names[0]
+		}
+		containers = append(containers, rc)
+	}
+	return containers, nil
+}
+
+func (dr *DockerRuntime) InspectContainer(id string) (*RuntimeContainer, error) {
+	inspectCont, err := dr.client.InspectContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	rc := &RuntimeContainer{
+		ID:      inspectCont.ID,
+		Name:    inspectCont.Name,
+		PID:     inspectCont.State.Pid,
+		Running: inspectCont.State.Running,
+		ImageID: inspectCont.Image,
+	}
+	if inspectCont.Config != nil {
+		rc.Image = inspectCont.Config.Image
+		rc.Labels = inspectCont.Config.Labels
+	}
+	rc.ImageDigest = dr.imageDigest(rc.ImageID, rc.Image)
+	return rc, nil
+}
+
+func (dr *DockerRuntime) MonitorEvents(events chan<- RuntimeEvent) error {
+	dr.events.StartMonitorEvents(func(event *dsamalba.Event, ec chan error, args ...interface{}) {
+		if event != nil {
+			events <- RuntimeEvent{ID: event.Id, Status: event.Status}
+		}
+	}, nil)
+	return nil
+}
+
+// imageDigest returns the repository digest, such as
+// "nginx@sha256:0d17b...", of the image with the given ID and name. An image
+// without digest, such as one built locally, returns an empty digest. The
+// images are only listed again when an image ID isn't known yet.
+func (dr *DockerRuntime) imageDigest(imageID, image string) string {
+	if imageID == "" {
+		return ""
+	}
+	dr.mutex.Lock()
+	defer dr.mutex.Unlock()
+	digests, ok := dr.imageDigests[imageID]
+	if !ok {
+		images, err := dr.client.ListImages(d.ListImagesOptions{Digests: true})
+		if err != nil {
+			log.Debug("Error while listing images: %s", err)
+			return ""
+		}
+		dr.imageDigests = make(map[string][]string, len(images)+1)
+		for _, img := range images {
+			dr.imageDigests[img.ID] = img.RepoDigests
+		}
+		// Images unknown to the daemon aren't looked up again.
+		digests, ok = dr.imageDigests[imageID]
+		if !ok {
+			dr.imageDigests[imageID] = nil
+		}
+	}
+	return repoDigest(digests, image)
+}
+
+// repoDigest returns, among the given digests of an image, the one of the
+// repository of the given image name, or the first one if none match.
+func repoDigest(digests []string, image string) string {
+	repo := image
+	if i := strings.Index(repo, "@"); i != -1 {
+		repo = repo[:i]
+	}
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		repo = repo[:i]
+	}
+	for _, digest := range digests {
+		if strings.HasPrefix(digest, repo+"@") {
+			return digest
+		}
+	}
+	if len(digests) != 0 {
+		return digests[0]
+	}
+	return ""
+}
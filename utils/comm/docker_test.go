@@ -0,0 +1,50 @@
+package comm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestImageDigest(t *testing.T) {
+	listings := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/images/json") {
+			http.NotFound(w, r)
+			return
+		}
+		listings++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"Id": "sha256:aaa", "RepoDigests": ["mirror/nginx@sha256:111", "nginx@sha256:222"]},
+			{"Id": "sha256:bbb", "RepoDigests": []}
+		]`))
+	}))
+	defer ts.Close()
+	dr, err := newDockerRuntimeAt(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tests := []struct {
+		imageID, image, want string
+	}{
+		{"sha256:aaa", "nginx:1.9", "nginx@sha256:222"},
+		{"sha256:aaa", "mirror/nginx", "mirror/nginx@sha256:111"},
+		{"sha256:aaa", "other", "mirror/nginx@sha256:111"},
+		{"sha256:bbb", "local", ""},
+		{"sha256:ccc", "removed", ""},
+		{"sha256:ccc", "removed", ""},
+		{"", "none", ""},
+	}
+	for _, tt := range tests {
+		if got := dr.imageDigest(tt.imageID, tt.image); got != tt.want {
+			t.Errorf("digest of %s %s:\ngot  %q\nwant %q", tt.imageID, tt.image, got, tt.want)
+		}
+	}
+	// The images are listed once for the first image and once for the
+	// unknown one.
+	if listings != 2 {
+		t.Errorf("number of image listings:\ngot  %d\nwant %d", listings, 2)
+	}
+}
@@ -0,0 +1,301 @@
+package comm
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	k8sPodNameLabel      = "io.kubernetes.pod.name"
+	k8sPodNamespaceLabel = "io.kubernetes.pod.namespace"
+	k8sPodUIDLabel       = "io.kubernetes.pod.uid"
+	k8sPodTemplateHash   = "pod-template-hash"
+	k8sTokenPath         = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+	k8sCACertPath        = "/var/run/secrets/kubernetes.io/serviceaccount/ca.crt"
+	kubeletTimeout       = 10 * time.Second
+	// kubeletMinRefresh is the minimum time between two pod listings, so
+	// containers without pod don't list the pods each time.
+	kubeletMinRefresh = 5 * time.Second
+	// kubeletMaxBackoff is the maximum time the kubelet isn't queried after
+	// failures.
+	kubeletMaxBackoff = 5 * time.Minute
+)
+
+// Enricher adds metadata, from a source other than Docker, to the containers
+// of a node.
+type Enricher interface {
+	// Prepare fetches the metadata of the container with the given ID and
+	// labels, if not fetched yet. It may query remote services, so it is
+	// called before the containers of the node are locked.
+	Prepare(id string, labels map[string]string) error
+	// Enrich sets the metadata fetched by Prepare on the given container.
+	// It doesn't block since the containers of the node are locked.
+	Enrich(cont *Container)
+}
+
+// KubernetesMetadata describes the pod a container belongs to.
+type KubernetesMetadata struct {
+	Namespace string
+	PodName   string
+	PodUID    string
+	// WorkloadKind and Workload are the kind, such as Deployment or
+	// DaemonSet, and the name of the controller owning the pod.
+	WorkloadKind string
+	Workload     string
+	PodLabels    map[string]string
+}
+
+// kubeletPodList is the subset of the PodList returned by the kubelet /pods
+// endpoint used to enrich containers.
+type kubeletPodList struct {
+	Items []kubeletPod `json:"items"`
+}
+
+type kubeletPod struct {
+	Metadata struct {
+		Name            string            `json:"name"`
+		Namespace       string            `json:"namespace"`
+		UID             string            `json:"uid"`
+		Labels          map[string]string `json:"labels"`
+		OwnerReferences []struct {
+			Kind       string `json:"kind"`
+			Name       string `json:"name"`
+			Controller bool   `json:"controller"`
+		} `json:"ownerReferences"`
+	} `json:"metadata"`
+	Status struct {
+		ContainerStatuses []struct {
+			ContainerID string `json:"containerID"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// KubernetesEnricher adds the metadata of the pod of each container, as
+// returned by the local kubelet, to the container. The io.kubernetes.* labels
+// set by the kubelet on containers are used when the kubelet can't be
+// reached, without the owner workload and the pod labels.
+type KubernetesEnricher struct {
+	url    string
+	token  string
+	client *http.Client
+
+	// refreshMutex serializes the pod listings, mutex protects the cached
+	// pods, which are read by Enrich during a listing.
+	refreshMutex sync.Mutex
+	mutex        sync.Mutex
+	pods         []kubeletPod
+	// refreshedAt is the time of the last pod listing, successful or not,
+	// and backoff the time the kubelet isn't queried after it.
+	refreshedAt time.Time
+	backoff     time.Duration
+}
+
+// NewKubernetesEnricher returns an enricher querying the kubelet at the given
+// URL, such as http://127.0.0.1:10255 or https://127.0.0.1:10250. The kubelet
+// certificate is verified against the CA in the KUBELET_CA_CERT environment
+// variable or, if not set, the one of the service account. Verification is
+// only skipped if KUBELET_INSECURE_SKIP_VERIFY is true. The service account
+// token, if any, is used to authenticate to the kubelet over https only.
+func NewKubernetesEnricher(url string) (*KubernetesEnricher, error) {
+	tlsConfig, err := kubeletTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	ke := &KubernetesEnricher{
+		url: strings.TrimRight(url, "/"),
+		client: &http.Client{
+			Timeout:   kubeletTimeout,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if req.URL.Scheme != "https" {
+					return fmt.Errorf("redirect to non https URL '%s'", req.URL)
+				}
+				return nil
+			},
+		},
+	}
+	if !strings.HasPrefix(ke.url, "https://") {
+		return ke, nil
+	}
+	if token, err := ioutil.ReadFile(k8sTokenPath); err == nil {
+		ke.token = strings.TrimSpace(string(token))
+	}
+	return ke, nil
+}
+
+// kubeletTLSConfig returns the TLS configuration of the kubelet client, with
+// the CA of KUBELET_CA_CERT, or of the service account if mounted, or without
+// verification if KUBELET_INSECURE_SKIP_VERIFY is true.
+func kubeletTLSConfig() (*tls.Config, error) {
+	if skip := os.Getenv("KUBELET_INSECURE_SKIP_VERIFY"); skip != "" {
+		insecure, err := strconv.ParseBool(skip)
+		if err != nil {
+			return nil, fmt.Errorf("invalid KUBELET_INSECURE_SKIP_VERIFY '%s': %s", skip, err)
+		}
+		if insecure {
+			log.Warning("Kubelet certificate won't be verified")
+			return &tls.Config{InsecureSkipVerify: true}, nil
+		}
+	}
+	caPath := os.Getenv("KUBELET_CA_CERT")
+	if caPath == "" {
+		if _, err := os.Stat(k8sCACertPath); err != nil {
+			// Verified against the system CAs.
+			return &tls.Config{}, nil
+		}
+		caPath = k8sCACertPath
+	}
+	ca, err := ioutil.ReadFile(caPath)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificate found in '%s'", caPath)
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+// Prepare lists the pods of the kubelet if the pod of the container with the
+// given ID and labels isn't in the cached pod list, such as for new pods.
+// Containers that don't belong to a pod are ignored.
+func (ke *KubernetesEnricher) Prepare(id string, labels map[string]string) error {
+	if labels[k8sPodUIDLabel] == "" && labels[k8sPodNameLabel] == "" {
+		return nil
+	}
+	ke.refreshMutex.Lock()
+	defer ke.refreshMutex.Unlock()
+	ke.mutex.Lock()
+	_, ok := ke.findPod(id, labels)
+	due := time.Since(ke.refreshedAt) >= ke.backoff
+	ke.mutex.Unlock()
+	if ok || !due {
+		return nil
+	}
+	return ke.refreshPods()
+}
+
+// Enrich sets the Kubernetes metadata of the given container from the cached
+// pod list, or from its labels if its pod isn't listed. Containers that don't
+// belong to a pod are left untouched.
+func (ke *KubernetesEnricher) Enrich(cont *Container) {
+	labels := cont.DockerLabels
+	if labels[k8sPodUIDLabel] == "" && labels[k8sPodNameLabel] == "" {
+		return
+	}
+	ke.mutex.Lock()
+	defer ke.mutex.Unlock()
+	if pod, ok := ke.findPod(cont.DockerID, labels); ok {
+		cont.Metadata.Kubernetes = kubernetesMetadataFromPod(pod)
+	} else {
+		cont.Metadata.Kubernetes = kubernetesMetadataFromLabels(labels)
+	}
+}
+
+// findPod returns the pod, from the cached pod list, the container with the
+// given ID and labels belongs to, by its pod UID or by its container ID.
+func (ke *KubernetesEnricher) findPod(id string, labels map[string]string) (*kubeletPod, bool) {
+	uid := labels[k8sPodUIDLabel]
+	for i := range ke.pods {
+		pod := &ke.pods[i]
+		if uid != "" && pod.Metadata.UID == uid {
+			return pod, true
+		}
+		for _, status := range pod.Status.ContainerStatuses {
+			// <runtime>://<container id>
+			if j := strings.Index(status.ContainerID, "://"); j != -1 &&
+				status.ContainerID[j+3:] == id {
+				return pod, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// refreshPods lists the pods of the kubelet. The kubelet isn't queried again
+// for kubeletMinRefresh after a listing, and for twice as long as the previous
+// time, up to kubeletMaxBackoff, after each failure. The cached pods aren't
+// locked during the request.
+func (ke *KubernetesEnricher) refreshPods() error {
+	pods, err := ke.listPods()
+	ke.mutex.Lock()
+	defer ke.mutex.Unlock()
+	ke.refreshedAt = time.Now()
+	if err != nil {
+		ke.backoff *= 2
+		if ke.backoff < kubeletMinRefresh {
+			ke.backoff = kubeletMinRefresh
+		} else if ke.backoff > kubeletMaxBackoff {
+			ke.backoff = kubeletMaxBackoff
+		}
+		return err
+	}
+	ke.pods = pods
+	ke.backoff = kubeletMinRefresh
+	return nil
+}
+
+// listPods returns the pods of the kubelet /pods endpoint.
+func (ke *KubernetesEnricher) listPods() ([]kubeletPod, error) {
+	req, err := http.NewRequest("GET", ke.url+"/pods", nil)
+	if err != nil {
+		return nil, err
+	}
+	if ke.token != "" && req.URL.Scheme == "https" {
+		req.Header.Set("Authorization", "Bearer "+ke.token)
+	}
+	resp, err := ke.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from kubelet: %s", resp.Status)
+	}
+	var podList kubeletPodList
+	if err := json.NewDecoder(resp.Body).Decode(&podList); err != nil {
+		return nil, err
+	}
+	return podList.Items, nil
+}
+
+func kubernetesMetadataFromLabels(labels map[string]string) *KubernetesMetadata {
+	return &KubernetesMetadata{
+		Namespace: labels[k8sPodNamespaceLabel],
+		PodName:   labels[k8sPodNameLabel],
+		PodUID:    labels[k8sPodUIDLabel],
+	}
+}
+
+func kubernetesMetadataFromPod(pod *kubeletPod) *KubernetesMetadata {
+	km := &KubernetesMetadata{
+		Namespace: pod.Metadata.Namespace,
+		PodName:   pod.Metadata.Name,
+		PodUID:    pod.Metadata.UID,
+		PodLabels: pod.Metadata.Labels,
+	}
+	for _, owner := range pod.Metadata.OwnerReferences {
+		if !owner.Controller {
+			continue
+		}
+		km.WorkloadKind, km.Workload = owner.Kind, owner.Name
+		// Pods of a Deployment are owned by a ReplicaSet named after the
+		// Deployment and the hash of the pod template.
+		hash := pod.Metadata.Labels[k8sPodTemplateHash]
+		if owner.Kind == "ReplicaSet" && hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+			km.WorkloadKind = "Deployment"
+			km.Workload = strings.TrimSuffix(owner.Name, "-"+hash)
+		}
+		break
+	}
+	return km
+}
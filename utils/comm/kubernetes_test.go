@@ -0,0 +1,230 @@
+package comm
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+const kubeletPods = `{
+  "kind": "PodList",
+  "items": [
+    {
+      "metadata": {
+        "name": "web-5d8f7c9b6-x2x9z",
+        "namespace": "shop",
+        "uid": "0b4e1bc2-6b1b-4e3c-9a4b-3f1c2d6e7a10",
+        "labels": {"app": "web", "pod-template-hash": "5d8f7c9b6"},
+        "ownerReferences": [
+          {"kind": "ReplicaSet", "name": "web-5d8f7c9b6", "controller": true}
+        ]
+      },
+      "status": {
+        "containerStatuses": [
+          {"name": "web", "containerID": "docker://4b825dc642cb"}
+        ]
+      }
+    },
+    {
+      "metadata": {
+        "name": "agent-7xk2p",
+        "namespace": "kube-system",
+        "uid": "9f1a2b3c-0000-4e3c-9a4b-3f1c2d6e7a11",
+        "ownerReferences": [
+          {"kind": "DaemonSet", "name": "agent", "controller": true}
+        ]
+      }
+    }
+  ]
+}`
+
+func newKubeletServer(t *testing.T, requests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.URL.Path != "/pods" {
+			t.Errorf("unexpected kubelet path:\ngot  %s\nwant %s", r.URL.Path, "/pods")
+		}
+		w.Write([]byte(kubeletPods))
+	}))
+}
+
+func TestKubernetesEnricher(t *testing.T) {
+	var requests int
+	ts := newKubeletServer(t, &requests)
+	defer ts.Close()
+	ke, err := NewKubernetesEnricher(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// Matched by container ID
+	cont := &Container{
+		DockerID:     "4b825dc642cb",
+		DockerLabels: map[string]string{k8sPodNameLabel: "web-5d8f7c9b6-x2x9z"},
+	}
+	if err := ke.Prepare(cont.DockerID, cont.DockerLabels); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ke.Enrich(cont)
+	km := cont.Metadata.Kubernetes
+	if km == nil {
+		t.Fatalf("container should have Kubernetes metadata")
+	}
+	if km.Namespace != "shop" || km.PodName != "web-5d8f7c9b6-x2x9z" || km.PodUID != "0b4e1bc2-6b1b-4e3c-9a4b-3f1c2d6e7a10" {
+		t.Errorf("unexpected pod:\ngot  %s/%s %s", km.Namespace, km.PodName, km.PodUID)
+	}
+	if km.WorkloadKind != "Deployment" || km.Workload != "web" {
+		t.Errorf("unexpected workload:\ngot  %s %s\nwant %s %s", km.WorkloadKind, km.Workload, "Deployment", "web")
+	}
+	if km.PodLabels["app"] != "web" {
+		t.Errorf("unexpected pod labels:\ngot  %v", km.PodLabels)
+	}
+
+	// Matched by pod UID from the cached pod list
+	cont = &Container{
+		DockerID:     "e69de29bb2d1",
+		DockerLabels: map[string]string{k8sPodUIDLabel: "9f1a2b3c-0000-4e3c-9a4b-3f1c2d6e7a11"},
+	}
+	if err := ke.Prepare(cont.DockerID, cont.DockerLabels); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ke.Enrich(cont)
+	if km := cont.Metadata.Kubernetes; km == nil || km.WorkloadKind != "DaemonSet" || km.Workload != "agent" {
+		t.Errorf("unexpected metadata:\ngot  %+v", km)
+	}
+	if requests != 1 {
+		t.Errorf("unexpected number of kubelet requests:\ngot  %d\nwant %d", requests, 1)
+	}
+
+	// Not a Kubernetes container
+	cont = &Container{DockerID: "d670460b4b4a"}
+	if err := ke.Prepare(cont.DockerID, cont.DockerLabels); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ke.Enrich(cont)
+	if cont.Metadata.Kubernetes != nil {
+		t.Errorf("container should not have Kubernetes metadata")
+	}
+
+	// Enrich doesn't query the kubelet for pods not prepared yet
+	cont = &Container{
+		DockerID:     "1c0b6f3a9e2d",
+		DockerLabels: map[string]string{k8sPodNameLabel: "web-5d8f7c9b6-k8m2q", k8sPodNamespaceLabel: "shop"},
+	}
+	ke.Enrich(cont)
+	if km := cont.Metadata.Kubernetes; km == nil || km.PodName != "web-5d8f7c9b6-k8m2q" || km.Workload != "" {
+		t.Errorf("unexpected metadata:\ngot  %+v", km)
+	}
+	if requests != 1 {
+		t.Errorf("unexpected number of kubelet requests:\ngot  %d\nwant %d", requests, 1)
+	}
+}
+
+func TestKubernetesEnricherLabelsFallback(t *testing.T) {
+	var requests int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer ts.Close()
+	ke, err := NewKubernetesEnricher(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	cont := &Container{
+		DockerID: "4b825dc642cb",
+		DockerLabels: map[string]string{
+			k8sPodNameLabel:      "web-5d8f7c9b6-x2x9z",
+			k8sPodNamespaceLabel: "shop",
+			k8sPodUIDLabel:       "0b4e1bc2-6b1b-4e3c-9a4b-3f1c2d6e7a10",
+		},
+	}
+	if err := ke.Prepare(cont.DockerID, cont.DockerLabels); err == nil {
+		t.Errorf("unreachable kubelet should return an error")
+	}
+	ke.Enrich(cont)
+	km := cont.Metadata.Kubernetes
+	if km == nil || km.Namespace != "shop" || km.PodName != "web-5d8f7c9b6-x2x9z" || km.PodUID != "0b4e1bc2-6b1b-4e3c-9a4b-3f1c2d6e7a10" {
+		t.Errorf("unexpected metadata:\ngot  %+v", km)
+	}
+
+	// The kubelet isn't queried again until the backoff expires.
+	cont.Metadata.Kubernetes = nil
+	if err := ke.Prepare(cont.DockerID, cont.DockerLabels); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	ke.Enrich(cont)
+	if km := cont.Metadata.Kubernetes; km == nil || km.PodName != "web-5d8f7c9b6-x2x9z" {
+		t.Errorf("unexpected metadata:\ngot  %+v", km)
+	}
+	if requests != 1 {
+		t.Errorf("unexpected number of kubelet requests:\ngot  %d\nwant %d", requests, 1)
+	}
+}
+
+func TestKubernetesEnricherTLS(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(kubeletPods))
+	}))
+	defer ts.Close()
+	caPath := filepath.Join(t.TempDir(), "ca.crt")
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ts.Certificate().Raw})
+	if err := ioutil.WriteFile(caPath, ca, 0644); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	tests := []struct {
+		name, caCert, skipVerify string
+		verified                 bool
+	}{
+		{"default", "", "", false},
+		{"KUBELET_CA_CERT", caPath, "", true},
+		{"KUBELET_INSECURE_SKIP_VERIFY", "", "true", true},
+		{"KUBELET_INSECURE_SKIP_VERIFY false", "", "false", false},
+	}
+	for _, tt := range tests {
+		t.Setenv("KUBELET_CA_CERT", tt.caCert)
+		t.Setenv("KUBELET_INSECURE_SKIP_VERIFY", tt.skipVerify)
+		ke, err := NewKubernetesEnricher(ts.URL)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", tt.name, err)
+		}
+		cont := &Container{
+			DockerID:     "4b825dc642cb",
+			DockerLabels: map[string]string{k8sPodNameLabel: "web-5d8f7c9b6-x2x9z"},
+		}
+		err = ke.Prepare(cont.DockerID, cont.DockerLabels)
+		if verified := err == nil; verified != tt.verified {
+			t.Errorf("%s: verified:\ngot  %t (%v)\nwant %t", tt.name, verified, err, tt.verified)
+		}
+	}
+	t.Setenv("KUBELET_INSECURE_SKIP_VERIFY", "maybe")
+	if _, err := NewKubernetesEnricher(ts.URL); err == nil {
+		t.Errorf("invalid KUBELET_INSECURE_SKIP_VERIFY should return an error")
+	}
+}
+
+func TestKubernetesEnricherTokenOverHTTP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if auth := r.Header.Get("Authorization"); auth != "" {
+			t.Errorf("token sent over http:\ngot  %s", auth)
+		}
+		w.Write([]byte(kubeletPods))
+	}))
+	defer ts.Close()
+	ke, err := NewKubernetesEnricher(ts.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ke.token = "secret"
+	cont := &Container{
+		DockerID:     "4b825dc642cb",
+		DockerLabels: map[string]string{k8sPodNameLabel: "web-5d8f7c9b6-x2x9z"},
+	}
+	if err := ke.Prepare(cont.DockerID, cont.DockerLabels); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	ke.Enrich(cont)
+}
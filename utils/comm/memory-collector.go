@@ -0,0 +1,83 @@
+package comm
+
+import (
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+// MemoryFamily is the metric family name of cgroup memory statistics.
+const MemoryFamily = "memory"
+
+// memoryFilesV1 are the cgroup v1 single value memory files reported.
+var memoryFilesV1 = []cgroupFile{
+	{"memory.usage_in_bytes", "usage", Gauge},
+	{"memory.max_usage_in_bytes", "max_usage", Gauge},
+	{"memory.limit_in_bytes", "limit", Gauge},
+	{"memory.failcnt", "failcnt", Counter},
+}
+
+// memoryKeysV1 are the cgroup v1 memory.stat entries and OOM kills reported.
+// memory.oom_control only has the oom_kill counter since linux 4.13.
+var memoryKeysV1 = []cgroupKey{
+	{file: "memory.stat", key: "rss", name: "rss", kind: Gauge},
+	{file: "memory.stat", key: "cache", name: "cache", kind: Gauge},
+	{file: "memory.stat", key: "swap", name: "swap", kind: Gauge},
+	{file: "memory.stat", key: "pgfault", name: "pgfault"},
+	{file: "memory.stat", key: "pgmajfault", name: "pgmajfault"},
+	{file: "memory.oom_control", key: "oom_kill", name: "oom_kill"},
+}
+
+// memoryFilesV2 are the cgroup v2 single value memory files reported, with
+// the same names as in cgroup v1.
+var memoryFilesV2 = []cgroupFile{
+	{"memory.current", "usage", Gauge},
+	{"memory.peak", "max_usage", Gauge},
+	{"memory.max", "limit", Gauge},
+	{"memory.swap.current", "swap", Gauge},
+}
+
+// memoryKeysV2 are the cgroup v2 memory.stat and memory.events entries
+// reported, with the same names as in cgroup v1.
+var memoryKeysV2 = []cgroupKey{
+	{file: "memory.stat", key: "anon", name: "rss", kind: Gauge},
+	{file: "memory.stat", key: "file", name: "cache", kind: Gauge},
+	{file: "memory.stat", key: "pgfault", name: "pgfault"},
+	{file: "memory.stat", key: "pgmajfault", name: "pgmajfault"},
+	{file: "memory.events", key: "max", name: "failcnt"},
+	{file: "memory.events", key: "oom_kill", name: "oom_kill"},
+}
+
+// MemoryCollector collects the cgroup memory statistics of containers,
+// including the number of times they were OOM killed.
+type MemoryCollector struct {
+	mode u.CgroupMode
+}
+
+// NewMemoryCollector returns a MemoryCollector of the given layout of the
+// cgroup hierarchies.
+func NewMemoryCollector(mode u.CgroupMode) *MemoryCollector {
+	return &MemoryCollector{mode: mode}
+}
+
+func (mc *MemoryCollector) Name() string {
+	return MemoryFamily
+}
+
+func (mc *MemoryCollector) Discover(cont *Container) error {
+	return cont.UpdateCgroupPaths()
+}
+
+// Collect returns the memory usage, in bytes, of the container as gauges and
+// its page faults, allocation failures and OOM kills as counters.
+func (mc *MemoryCollector) Collect(cont *Container) ([]Sample, error) {
+	dir := cont.CgroupDir(mc.mode, "memory")
+	if dir == "" {
+		return nil, errNoCgroup
+	}
+	files, keys := memoryFilesV1, memoryKeysV1
+	if mc.mode == u.CgroupV2 {
+		files, keys = memoryFilesV2, memoryKeysV2
+	}
+	f := cont.Family(MemoryFamily)
+	samples := storeCgroupFiles(f, cont, dir, files)
+	return append(samples, storeCgroupKeys(f, cont, dir, keys)...), nil
+}
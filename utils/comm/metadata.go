@@ -0,0 +1,65 @@
+package comm
+
+import (
+	"regexp"
+)
+
+const (
+	composeProjectLabel = "com.docker.compose.project"
+	composeServiceLabel = "com.docker.compose.service"
+	swarmServiceIDLabel = "com.docker.swarm.service.id"
+	swarmTaskIDLabel    = "com.docker.swarm.task.id"
+)
+
+// LabelFilter selects the keys of the container labels copied to the
+// metadata of containers. No label is copied if nil.
+var LabelFilter *regexp.Regexp
+
+// ContainerMetadata describes what a container runs, as given by its image and
+// its labels, so its metrics can be aggregated by service.
+type ContainerMetadata struct {
+	Image       string
+	ImageID     string
+	ImageDigest string
+	// Labels are the container labels selected by LabelFilter.
+	Labels         map[string]string
+	ComposeProject string
+	ComposeService string
+	SwarmServiceID string
+	SwarmTaskID    string
+	// Kubernetes is the metadata of the pod of the container, set by the
+	// KubernetesEnricher.
+	Kubernetes *KubernetesMetadata
+}
+
+// newContainerMetadata returns the metadata of the given container.
+func newContainerMetadata(rc *RuntimeContainer) ContainerMetadata {
+	return ContainerMetadata{
+		Image:          rc.Image,
+		ImageID:        rc.ImageID,
+		ImageDigest:    rc.ImageDigest,
+		Labels:         filterLabels(rc.Labels, LabelFilter),
+		ComposeProject: rc.Labels[composeProjectLabel],
+		ComposeService: rc.Labels[composeServiceLabel],
+		SwarmServiceID: rc.Labels[swarmServiceIDLabel],
+		SwarmTaskID:    rc.Labels[swarmTaskIDLabel],
+	}
+}
+
+// filterLabels returns the labels whose key matches the given filter.
+func filterLabels(labels map[string]string, filter *regexp.Regexp) map[string]string {
+	if filter == nil {
+		return nil
+	}
+	var selected map[string]string
+	for key, value := range labels {
+		if !filter.MatchString(key) {
+			continue
+		}
+		if selected == nil {
+			selected = map[string]string{}
+		}
+		selected[key] = value
+	}
+	return selected
+}
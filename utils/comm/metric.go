@@ -0,0 +1,156 @@
+package comm
+
+import (
+	"sort"
+	"strings"
+)
+
+// MetricKind tells how the value of a metric is reported.
+type MetricKind int
+
+const (
+	// Counter metrics only increase over time and are reported as the
+	// difference since the last read.
+	Counter MetricKind = iota
+	// Gauge metrics can go up and down and are reported as read.
+	Gauge
+)
+
+func (k MetricKind) String() string {
+	switch k {
+	case Gauge:
+		return "gauge"
+	default:
+		return "counter"
+	}
+}
+
+// Metric is a value read by a Collector that is kept between ticks so the
+// difference since the last read can be reported.
+type Metric struct {
+	Name          string
+	Kind          MetricKind
+	Labels        map[string]string
+	ValueRead     int64
+	LastValueRead int64
+	CurrentValue  int64
+	// read is false until the metric is read for the first time.
+	read bool
+}
+
+// MetricFamily holds the metrics of a container read by a single Collector.
+type MetricFamily struct {
+	Name    string
+	Metrics map[string]*Metric
+}
+
+// Family returns the metric family of the container with the given name,
+// creating it if it doesn't exist yet.
+func (cont *Container) Family(name string) *MetricFamily {
+	return family(&cont.MetricFamilies, name)
+}
+
+// Family returns the metric family of the node itself with the given name,
+// creating it if it doesn't exist yet.
+func (n *Node) Family(name string) *MetricFamily {
+	return family(&n.MetricFamilies, name)
+}
+
+func family(families *map[string]*MetricFamily, name string) *MetricFamily {
+	if *families == nil {
+		*families = map[string]*MetricFamily{}
+	}
+	f, ok := (*families)[name]
+	if !ok {
+		f = &MetricFamily{
+			Name:    name,
+			Metrics: map[string]*Metric{},
+		}
+		(*families)[name] = f
+	}
+	return f
+}
+
+// Update stores value as the last read of the counter with the given name and
+// labels, and returns a sample of the container with the difference since the
+// previous read, the same way Container.UpdateLastValue does. The first read of
+// a counter is its total since the container started, so its difference is 0.
+// Samples of the node itself have a nil container.
+func (f *MetricFamily) Update(cont *Container, name string, labels map[string]string, value int64) Sample {
+	m := f.metric(name, Counter, labels)
+	m.ValueRead = value
+	if !m.read {
+		m.LastValueRead, m.read = value, true
+	}
+	m.CurrentValue, m.LastValueRead = m.ValueRead-m.LastValueRead, m.ValueRead
+	return f.sample(cont, m)
+}
+
+// Set stores value as the last read of the gauge with the given name and
+// labels, and returns a sample of the container with the absolute value.
+func (f *MetricFamily) Set(cont *Container, name string, labels map[string]string, value int64) Sample {
+	m := f.metric(name, Gauge, labels)
+	m.ValueRead, m.LastValueRead, m.CurrentValue = value, value, value
+	return f.sample(cont, m)
+}
+
+// Store stores value as the last read of the metric with the given name, kind
+// and labels, and returns a sample of the container.
+func (f *MetricFamily) Store(cont *Container, name string, kind MetricKind, labels map[string]string, value int64) Sample {
+	if kind == Gauge {
+		return f.Set(cont, name, labels, value)
+	}
+	return f.Update(cont, name, labels, value)
+}
+
+// Forget removes all metrics with the given name, such as the gauges of top
+// entries that might not be reported again.
+func (f *MetricFamily) Forget(name string) {
+	for key, m := range f.Metrics {
+		if m.Name == name {
+			delete(f.Metrics, key)
+		}
+	}
+}
+
+func (f *MetricFamily) metric(name string, kind MetricKind, labels map[string]string) *Metric {
+	key := metricKey(name, labels)
+	m, ok := f.Metrics[key]
+	if !ok {
+		m = &Metric{
+			Name:   name,
+			Kind:   kind,
+			Labels: labels,
+		}
+		f.Metrics[key] = m
+	}
+	return m
+}
+
+func (f *MetricFamily) sample(cont *Container, m *Metric) Sample {
+	return Sample{
+		Family:    f.Name,
+		Name:      m.Name,
+		Kind:      m.Kind,
+		Labels:    m.Labels,
+		Value:     m.CurrentValue,
+		ValueRead: m.ValueRead,
+		Container: cont,
+	}
+}
+
+func metricKey(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{name}
+	for _, k := range keys {
+		parts = append(parts, k+"="+labels[k])
+	}
+	return strings.Join(parts, ",")
+}
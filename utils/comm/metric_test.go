@@ -0,0 +1,110 @@
+package comm
+
+import (
+	"testing"
+)
+
+func TestMetricFamilyUpdate(t *testing.T) {
+	cont := &Container{Name: "/web"}
+	f := cont.Family("fake")
+	labels := map[string]string{"cpu": "0"}
+	for i, tt := range []struct {
+		value, want int64
+	}{
+		// The first read is the total since the container started.
+		{1000, 0},
+		{1500, 500},
+		{1500, 0},
+		{4000, 2500},
+	} {
+		s := f.Update(cont, "usage", labels, tt.value)
+		if s.Value != tt.want || s.ValueRead != tt.value {
+			t.Errorf("read %d:\ngot  value %d, read %d\nwant value %d, read %d", i, s.Value, s.ValueRead, tt.want, tt.value)
+		}
+		if s.Family != "fake" || s.Name != "usage" || s.Container != cont || s.Labels["cpu"] != "0" {
+			t.Errorf("read %d: invalid sample %+v", i, s)
+		}
+	}
+	// Metrics with other labels are independent.
+	if s := f.Update(cont, "usage", map[string]string{"cpu": "1"}, 300); s.Value != 0 {
+		t.Errorf("first read of another metric:\ngot  %d\nwant %d", s.Value, 0)
+	}
+	if len(f.Metrics) != 2 {
+		t.Errorf("invalid number of metrics:\ngot  %d\nwant %d", len(f.Metrics), 2)
+	}
+}
+
+func TestMetricFamilySetAndStore(t *testing.T) {
+	n := &Node{}
+	f := n.Family("fake")
+	if s := f.Set(nil, "tasks", nil, 10); s.Value != 10 || s.ValueRead != 10 || s.Container != nil {
+		t.Errorf("invalid gauge sample:\ngot  %+v\nwant value %d", s, 10)
+	}
+	if s := f.Set(nil, "tasks", nil, 4); s.Value != 4 {
+		t.Errorf("invalid gauge sample:\ngot  %d\nwant %d", s.Value, 4)
+	}
+	f.Store(nil, "limit", Gauge, nil, 100)
+	if s := f.Store(nil, "limit", Gauge, nil, 80); s.Value != 80 {
+		t.Errorf("invalid stored gauge:\ngot  %d\nwant %d", s.Value, 80)
+	}
+	f.Store(nil, "events", Counter, nil, 100)
+	if s := f.Store(nil, "events", Counter, nil, 130); s.Value != 30 || s.ValueRead != 130 {
+		t.Errorf("invalid stored counter:\ngot  %d\nwant %d", s.Value, 30)
+	}
+	if n.Family("fake") != f {
+		t.Errorf("the family of the node should be kept between reads")
+	}
+	kinds := []struct {
+		sample Sample
+		kind   MetricKind
+		name   string
+	}{
+		{f.Update(nil, "rx_bytes", nil, 1), Counter, "counter"},
+		{f.Set(nil, "tasks", nil, 1), Gauge, "gauge"},
+		{f.Store(nil, "events", Counter, nil, 1), Counter, "counter"},
+		{f.Store(nil, "limit", Gauge, nil, 1), Gauge, "gauge"},
+	}
+	for _, tt := range kinds {
+		if tt.sample.Kind != tt.kind || tt.sample.Kind.String() != tt.name {
+			t.Errorf("kind of %s:\ngot  %s\nwant %s", tt.sample.Name, tt.sample.Kind, tt.name)
+		}
+		if m := f.Metrics[tt.sample.Name]; m == nil || m.Kind != tt.kind {
+			t.Errorf("stored kind of %s:\ngot  %v\nwant %s", tt.sample.Name, m, tt.name)
+		}
+	}
+}
+
+func TestMetricFamilyForget(t *testing.T) {
+	f := (&Container{}).Family("fake")
+	f.Set(nil, "top_rss", map[string]string{"process": "a"}, 1)
+	f.Set(nil, "top_rss", map[string]string{"process": "b"}, 2)
+	f.Update(nil, "forks", nil, 5)
+	f.Forget("top_rss")
+	if len(f.Metrics) != 1 || f.Metrics["forks"] == nil {
+		t.Errorf("invalid metrics after forget:\ngot  %v\nwant %s", f.Metrics, "forks")
+	}
+	// A forgotten counter starts over.
+	f.Forget("forks")
+	if s := f.Update(nil, "forks", nil, 50); s.Value != 0 {
+		t.Errorf("first read of a forgotten counter:\ngot  %d\nwant %d", s.Value, 0)
+	}
+}
+
+func TestMetricKey(t *testing.T) {
+	tests := []struct {
+		labels map[string]string
+		want   string
+	}{
+		{nil, "usage"},
+		{map[string]string{}, "usage"},
+		{map[string]string{"device": "sda"}, "usage,device=sda"},
+		{map[string]string{"op": "read", "device": "sda", "cpu": "1"}, "usage,cpu=1,device=sda,op=read"},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 5; i++ {
+			if got := metricKey("usage", tt.labels); got != tt.want {
+				t.Errorf("metricKey(%v):\ngot  %s\nwant %s", tt.labels, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package comm
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+// NetNSIDPrefix is the prefix of the synthetic ID of the pseudo containers of
+// network namespaces that don't belong to containers.
+const NetNSIDPrefix = "netns-"
+
+// IsNetNS returns true if the given container is the pseudo container of a
+// network namespace.
+func (cont *Container) IsNetNS() bool {
+	return strings.HasPrefix(cont.DockerID, NetNSIDPrefix)
+}
+
+// netNSID returns the synthetic ID of the network namespace with the given
+// identifier, such as "net:[4026532281]".
+func netNSID(ns string) string {
+	return NetNSIDPrefix + strings.TrimSuffix(strings.TrimPrefix(ns, "net:["), "]")
+}
+
+// DiscoverNetNS returns a pseudo container for each network namespace, other
+// than the host's, the ones of the given containers and the ones of the
+// processes of the given untracked PIDs, created by "ip netns" or used by a
+// process, such as a systemd-nspawn machine. Untracked PIDs are the ones of
+// runtime containers that aren't in containers, such as the filtered out
+// ones. Pseudo containers of processes are named after the command of their
+// first process and have its PID. Pseudo containers of "ip netns" are named
+// after the namespace and only have a PID if a process uses the namespace.
+func DiscoverNetNS(containers []Container, untrackedPIDs []int) ([]Container, error) {
+	log.Debug("")
+	hostNS, err := u.ReadNamespace(u.HostPID, "net")
+	if err != nil {
+		return nil, err
+	}
+	known := map[string]bool{hostNS: true}
+	for _, cont := range containers {
+		if cont.IsNetNS() || !cont.IsActive {
+			continue
+		}
+		if ns, err := u.ReadNamespace(cont.PID, "net"); err == nil {
+			known[ns] = true
+		}
+	}
+	for _, pid := range untrackedPIDs {
+		if ns, err := u.ReadNamespace(pid, "net"); err == nil {
+			known[ns] = true
+		}
+	}
+	hn, err := os.Hostname()
+	if err != nil {
+		log.Error("Error while getting the host name: %v", err)
+	}
+
+	pseudos := map[string]*Container{}
+	named, err := u.ListNamedNetNS()
+	if err != nil {
+		log.Debug("Error while listing named network namespaces: %s", err)
+	}
+	for name, path := range named {
+		ns, err := u.ReadNamespaceFile(path)
+		if err != nil || known[ns] {
+			continue
+		}
+		pseudos[ns] = &Container{
+			DockerID:  netNSID(ns),
+			Name:      "/netns/" + name,
+			NodeName:  hn,
+			NetNSPath: path,
+			IsActive:  true,
+		}
+	}
+
+	pids, err := u.ListPIDs()
+	if err != nil {
+		return nil, err
+	}
+	sort.Ints(pids)
+	for _, pid := range pids {
+		ns, err := u.ReadNamespace(pid, "net")
+		if err != nil || known[ns] {
+			continue
+		}
+		if pseudo, ok := pseudos[ns]; ok {
+			if pseudo.PID == 0 {
+				pseudo.PID = pid
+			}
+			continue
+		}
+		name := strconv.Itoa(pid)
+		if ps, err := u.ReadProcessStat(pid); err == nil {
+			name = ps.Command + "-" + name
+		}
+		pseudos[ns] = &Container{
+			DockerID: netNSID(ns),
+			Name:     "/" + name,
+			NodeName: hn,
+			PID:      pid,
+			IsActive: true,
+		}
+	}
+
+	found := make([]Container, 0, len(pseudos))
+	for _, pseudo := range pseudos {
+		found = append(found, *pseudo)
+	}
+	return found, nil
+}
+
+// UpdateNetNS reads the network namespace of the container and whether it is
+// the one of the host.
+func (cont *Container) UpdateNetNS() {
+	var err error
+	if cont.PID == 0 && cont.NetNSPath != "" {
+		cont.NetNS, err = u.ReadNamespaceFile(cont.NetNSPath)
+	} else {
+		cont.NetNS, err = u.ReadNamespace(cont.PID, "net")
+	}
+	if err != nil {
+		log.Debug("Error while reading network namespace of '%s': %s", cont.Name, err)
+		cont.NetNS = ""
+	}
+	hostNS, err := u.ReadNamespace(u.HostPID, "net")
+	cont.HostNetwork = err == nil && cont.NetNS != "" && cont.NetNS == hostNS
+}
+
+// UpdateNetNSGroups groups the active containers by network namespace, such
+// as the containers of a pod or the ones started with --net container:X.
+// The metrics of a network namespace are collected once, from the owner of its
+// group, which is the container already owning it or otherwise the one with
+// the lowest PID, usually the pod sandbox or the parent container. The group
+// is named after the pod, for Kubernetes, or after the owner.
+func (n *Node) UpdateNetNSGroups() {
+	log.Debug("")
+	wasOwner := make([]bool, len(n.Containers))
+	for i := range n.Containers {
+		wasOwner[i] = n.Containers[i].NetNSOwner
+	}
+	owners := map[string]*Container{}
+	for i := range n.Containers {
+		cont := &n.Containers[i]
+		if !cont.IsActive {
+			cont.NetNSOwner = false
+			continue
+		}
+		cont.UpdateNetNS()
+		if cont.NetNSOwner && cont.NetNS != "" && owners[cont.NetNS] == nil {
+			owners[cont.NetNS] = cont
+		}
+	}
+	for i := range n.Containers {
+		cont := &n.Containers[i]
+		if !cont.IsActive || cont.NetNS == "" {
+			continue
+		}
+		owner := owners[cont.NetNS]
+		if owner == nil || (!owner.NetNSOwner && cont.PID < owner.PID) {
+			owners[cont.NetNS] = cont
+		}
+	}
+	for i := range n.Containers {
+		cont := &n.Containers[i]
+		if !cont.IsActive {
+			continue
+		}
+		if cont.NetNS == "" {
+			// Unknown namespace, collected on its own.
+			cont.NetNSOwner, cont.NetNSGroup = true, cont.Name
+			continue
+		}
+		owner := owners[cont.NetNS]
+		cont.NetNSOwner = owner == cont
+		cont.NetNSGroup = owner.netNSGroupName()
+		if cont.NetNSOwner && !wasOwner[i] {
+			cont.forgetNetNSMetrics()
+		}
+	}
+}
+
+// forgetNetNSMetrics discards the last read of the metrics of the network
+// namespace of a container that becomes its owner, such as when the previous
+// owner died. They were last read, if ever, when the container owned it
+// before, so the difference since then isn't reported.
+func (cont *Container) forgetNetNSMetrics() {
+	for i := range cont.NetworkInterfaces {
+		stats := cont.NetworkInterfaces[i].NetworkStats
+		for j := range stats {
+			stats[j].read = false
+		}
+	}
+	delete(cont.MetricFamilies, SNMPFamily)
+	delete(cont.MetricFamilies, SocketsFamily)
+}
+
+func (cont *Container) netNSGroupName() string {
+	if km := cont.Metadata.Kubernetes; km != nil && km.PodName != "" {
+		return km.Namespace + "/" + km.PodName
+	}
+	if pod := cont.DockerLabels[k8sPodNameLabel]; pod != "" {
+		return cont.DockerLabels[k8sPodNamespaceLabel] + "/" + pod
+	}
+	return cont.Name
+}
@@ -0,0 +1,100 @@
+package comm
+
+import (
+	"os"
+	"testing"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+func TestNetNSID(t *testing.T) {
+	tests := []struct {
+		ns, want string
+	}{
+		{"net:[4026532281]", "netns-4026532281"},
+		{"4026532281", "netns-4026532281"},
+		{"", "netns-"},
+	}
+	for _, tt := range tests {
+		if got := netNSID(tt.ns); got != tt.want {
+			t.Errorf("ID of %q:\ngot  %s\nwant %s", tt.ns, got, tt.want)
+		}
+		if cont := (Container{DockerID: netNSID(tt.ns)}); !cont.IsNetNS() {
+			t.Errorf("container of %q should be a network namespace", tt.ns)
+		}
+	}
+	if cont := (Container{DockerID: "4b825dc642cb"}); cont.IsNetNS() {
+		t.Errorf("container shouldn't be a network namespace")
+	}
+}
+
+func TestUpdateNetNSGroupsNewOwner(t *testing.T) {
+	pid := os.Getpid()
+	if _, err := u.ReadNamespace(pid, "net"); err != nil {
+		t.Skipf("unable to read network namespace: %s", err)
+	}
+	stats := func(valueRead, lastValueRead int64) []NetworkInterface {
+		return []NetworkInterface{{
+			Name:     "eth0",
+			IsActive: true,
+			NetworkStats: []NetworkStat{
+				{Name: "rx_bytes", ValueRead: valueRead, LastValueRead: lastValueRead, read: true},
+			},
+		}}
+	}
+	n := &Node{Containers: []Container{
+		{DockerID: "owner", Name: "/owner", PID: pid, IsActive: true, NetNSOwner: true},
+		{DockerID: "member", Name: "/member", PID: pid, IsActive: true, NetworkInterfaces: stats(1000, 400)},
+	}}
+	n.Containers[1].Family(SNMPFamily).Update(&n.Containers[1], "tcp_out_segs", nil, 10)
+	n.UpdateNetNSGroups()
+	if !n.Containers[0].NetNSOwner || n.Containers[1].NetNSOwner || n.Containers[1].NetNSGroup != "/owner" {
+		t.Fatalf("unexpected owner: %+v", n.Containers)
+	}
+
+	// The owner dies and the member, which owned the namespace before,
+	// becomes the owner without reporting the difference since then.
+	n.Containers[0].IsActive = false
+	n.UpdateNetNSGroups()
+	member := &n.Containers[1]
+	if !member.NetNSOwner {
+		t.Fatalf("member should own the network namespace")
+	}
+	member.NetworkInterfaces[0].NetworkStats[0].ValueRead = 5000
+	member.UpdateLastValue()
+	if got := member.NetworkInterfaces[0].NetworkStats[0].CurrentValue; got != 0 {
+		t.Errorf("difference of the first read of the new owner:\ngot  %d\nwant %d", got, 0)
+	}
+	if s := member.Family(SNMPFamily).Update(member, "tcp_out_segs", nil, 50); s.Value != 0 {
+		t.Errorf("difference of the first SNMP read of the new owner:\ngot  %d\nwant %d", s.Value, 0)
+	}
+	member.NetworkInterfaces[0].NetworkStats[0].ValueRead = 5300
+	member.UpdateLastValue()
+	if got := member.NetworkInterfaces[0].NetworkStats[0].CurrentValue; got != 300 {
+		t.Errorf("difference of the second read of the new owner:\ngot  %d\nwant %d", got, 300)
+	}
+
+	// Owners keep their reads.
+	n.UpdateNetNSGroups()
+	member.NetworkInterfaces[0].NetworkStats[0].ValueRead = 5400
+	member.UpdateLastValue()
+	if got := member.NetworkInterfaces[0].NetworkStats[0].CurrentValue; got != 100 {
+		t.Errorf("difference of the owner:\ngot  %d\nwant %d", got, 100)
+	}
+}
+
+func TestUpdateLastValueFirstRead(t *testing.T) {
+	netInters := []NetworkInterface{{
+		Name:         "eth0",
+		NetworkStats: []NetworkStat{{Name: "rx_bytes", ValueRead: 1 << 40}},
+	}}
+	updateLastValue(netInters)
+	if got := netInters[0].NetworkStats[0].CurrentValue; got != 0 {
+		t.Errorf("difference of the first read:\ngot  %d\nwant %d", got, 0)
+	}
+	netInters[0].NetworkStats[0].ValueRead += 42
+	updateLastValue(netInters)
+	if got := netInters[0].NetworkStats[0].CurrentValue; got != 42 {
+		t.Errorf("difference of the second read:\ngot  %d\nwant %d", got, 42)
+	}
+}
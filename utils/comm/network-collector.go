@@ -0,0 +1,78 @@
+package comm
+
+// NetworkFamily is the metric family name of network interface statistics.
+const NetworkFamily = "network"
+
+// NetworkCollector collects the network interface statistics of containers.
+type NetworkCollector struct{}
+
+func NewNetworkCollector() *NetworkCollector {
+	return &NetworkCollector{}
+}
+
+func (nc *NetworkCollector) Name() string {
+	return NetworkFamily
+}
+
+func (nc *NetworkCollector) netNSScoped() {}
+
+func (nc *NetworkCollector) Discover(cont *Container) error {
+	if err := cont.UpdateNetInterfaces(); err != nil {
+		return err
+	}
+	nc.updateHostInterfaces(cont)
+	return nil
+}
+
+// updateHostInterfaces maps the network interfaces of the given container to
+// the host interfaces. Failing to do so doesn't prevent the statistics from
+// being collected.
+func (nc *NetworkCollector) updateHostInterfaces(cont *Container) {
+	if err := cont.UpdateHostInterfaces(); err != nil {
+		log.Debug("Unable to map host interfaces of '%s': %s", cont.Name, err)
+	}
+}
+
+// Collect returns, for every statistic of each active network interface of
+// the given container, the difference since the last collection.
+func (nc *NetworkCollector) Collect(cont *Container) ([]Sample, error) {
+	if err := cont.UpdateNetworkStats(); err != nil {
+		return nil, err
+	}
+	nc.updateHostInterfaces(cont)
+	cont.UpdateLastValue()
+	return networkSamples(cont, cont.NetworkInterfaces), nil
+}
+
+// CollectNode returns, for every statistic of each network interface of the
+// host, the difference since the last collection.
+func (nc *NetworkCollector) CollectNode(node *Node) ([]Sample, error) {
+	if err := node.UpdateNetworkStats(); err != nil {
+		return nil, err
+	}
+	node.UpdateLastValue()
+	return networkSamples(nil, node.NetworkInterfaces), nil
+}
+
+func networkSamples(cont *Container, netInters []NetworkInterface) []Sample {
+	var samples []Sample
+	for i := range netInters {
+		netInter := &netInters[i]
+		if !netInter.IsActive {
+			continue
+		}
+		for _, stat := range netInter.NetworkStats {
+			samples = append(samples, Sample{
+				Family:    NetworkFamily,
+				Name:      stat.Name,
+				Kind:      Counter,
+				Labels:    map[string]string{InterfaceLabel: netInter.Name},
+				Value:     stat.CurrentValue,
+				ValueRead: stat.ValueRead,
+				Container: cont,
+				Interface: netInter,
+			})
+		}
+	}
+	return samples
+}
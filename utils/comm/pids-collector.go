@@ -0,0 +1,44 @@
+package comm
+
+import (
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+// PidsFamily is the metric family name of cgroup process numbers.
+const PidsFamily = "pids"
+
+// pidsFiles are the pids controller files reported, which have the same
+// names in cgroup v1 and v2.
+var pidsFiles = []cgroupFile{
+	{"pids.current", "current", Gauge},
+	{"pids.max", "limit", Gauge},
+}
+
+// PidsCollector collects the number of processes and threads of containers
+// from the pids controller.
+type PidsCollector struct {
+	mode u.CgroupMode
+}
+
+// NewPidsCollector returns a PidsCollector of the given layout of the cgroup
+// hierarchies.
+func NewPidsCollector(mode u.CgroupMode) *PidsCollector {
+	return &PidsCollector{mode: mode}
+}
+
+func (pc *PidsCollector) Name() string {
+	return PidsFamily
+}
+
+func (pc *PidsCollector) Discover(cont *Container) error {
+	return cont.UpdateCgroupPaths()
+}
+
+// Collect returns the number of tasks of the container and its limit.
+func (pc *PidsCollector) Collect(cont *Container) ([]Sample, error) {
+	dir := cont.CgroupDir(pc.mode, "pids")
+	if dir == "" {
+		return nil, errNoCgroup
+	}
+	return storeCgroupFiles(cont.Family(PidsFamily), cont, dir, pidsFiles), nil
+}
@@ -0,0 +1,109 @@
+package comm
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	// PodmanRuntimeName is the name of the Podman runtime.
+	PodmanRuntimeName     = "podman"
+	podmanDefaultEndpoint = "unix:///run/podman/podman.sock"
+)
+
+// podmanEvents maps the status of Podman events to the status of the
+// equivalent Docker events.
+var podmanEvents = map[string]string{
+	"died":   EventDie,
+	"remove": EventDestroy,
+}
+
+// PodmanRuntime is the Podman runtime, reached through the Docker compatible
+// API of its socket.
+type PodmanRuntime struct {
+	*DockerRuntime
+}
+
+// NewPodmanRuntime returns a client of the Podman socket set in the
+// PODMAN_HOST environment variable. When not set, the rootless socket of the
+// user, in $XDG_RUNTIME_DIR/podman/podman.sock, is used if it exists and the
+// rootful socket otherwise.
+func NewPodmanRuntime() (*PodmanRuntime, error) {
+	dr, err := newDockerRuntimeAt(podmanEndpoint())
+	if err != nil {
+		return nil, err
+	}
+	return &PodmanRuntime{dr}, nil
+}
+
+func podmanEndpoint() string {
+	if endpoint := os.Getenv("PODMAN_HOST"); endpoint != "" {
+		return endpoint
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		socket := runtimeDir + "/podman/podman.sock"
+		if _, err := os.Stat(socket); err == nil {
+			return "unix://" + socket
+		}
+	}
+	return podmanDefaultEndpoint
+}
+
+func (pr *PodmanRuntime) Name() string {
+	return PodmanRuntimeName
+}
+
+// InspectContainer returns the container with the given ID. Older Podman
+// versions return names without the leading slash Docker uses.
+func (pr *PodmanRuntime) InspectContainer(id string) (*RuntimeContainer, error) {
+	rc, err := pr.DockerRuntime.InspectContainer(id)
+	if err != nil {
+		return nil, err
+	}
+	rc.Name = podmanContainerName(rc.Name)
+	return rc, nil
+}
+
+// ListContainers returns all containers with the same names as returned by
+// InspectContainer.
+func (pr *PodmanRuntime) ListContainers() ([]RuntimeContainer, error) {
+	containers, err := pr.DockerRuntime.ListContainers()
+	if err != nil {
+		return nil, err
+	}
+	for i := range containers {
+		containers[i].Name = podmanContainerName(containers[i].Name)
+	}
+	return containers, nil
+}
+
+// podmanContainerName returns the given name with the leading slash Docker
+// uses.
+func podmanContainerName(name string) string {
+	if !strings.HasPrefix(name, "/") {
+		return "/" + name
+	}
+	return name
+}
+
+// MonitorEvents sends the events of all containers, with their status
+// translated to the Docker ones, to the given channel.
+func (pr *PodmanRuntime) MonitorEvents(events chan<- RuntimeEvent) error {
+	podmanEventsCh := make(chan RuntimeEvent)
+	if err := pr.DockerRuntime.MonitorEvents(podmanEventsCh); err != nil {
+		return err
+	}
+	go translatePodmanEvents(podmanEventsCh, events)
+	return nil
+}
+
+// translatePodmanEvents sends the Podman events received, with their status
+// translated to the Docker ones, until podmanEventsCh is closed.
+func translatePodmanEvents(podmanEventsCh <-chan RuntimeEvent, events chan<- RuntimeEvent) {
+	for event := range podmanEventsCh {
+		if status, ok := podmanEvents[event.Status]; ok {
+			event.Status = status
+		}
+		events <- event
+	}
+}
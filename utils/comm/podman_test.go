@@ -0,0 +1,102 @@
+package comm
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPodmanEndpoint(t *testing.T) {
+	runtimeDir := t.TempDir()
+	socket := filepath.Join(runtimeDir, "podman", "podman.sock")
+	tests := []struct {
+		name, podmanHost, runtimeDir string
+		socket                       bool
+		want                         string
+	}{
+		{"PODMAN_HOST", "tcp://127.0.0.1:8080", runtimeDir, true, "tcp://127.0.0.1:8080"},
+		{"rootless socket", "", runtimeDir, true, "unix://" + socket},
+		{"missing rootless socket", "", runtimeDir, false, podmanDefaultEndpoint},
+		{"no XDG_RUNTIME_DIR", "", "", true, podmanDefaultEndpoint},
+	}
+	for _, tt := range tests {
+		os.RemoveAll(filepath.Dir(socket))
+		if tt.socket {
+			if err := os.MkdirAll(filepath.Dir(socket), 0755); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			f, err := os.Create(socket)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			f.Close()
+		}
+		t.Setenv("PODMAN_HOST", tt.podmanHost)
+		t.Setenv("XDG_RUNTIME_DIR", tt.runtimeDir)
+		if got := podmanEndpoint(); got != tt.want {
+			t.Errorf("%s:\ngot  %s\nwant %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTranslatePodmanEvents(t *testing.T) {
+	podmanEventsCh := make(chan RuntimeEvent, 4)
+	podmanEventsCh <- RuntimeEvent{ID: "a", Status: EventStart}
+	podmanEventsCh <- RuntimeEvent{ID: "a", Status: "died"}
+	podmanEventsCh <- RuntimeEvent{ID: "a", Status: "remove"}
+	podmanEventsCh <- RuntimeEvent{ID: "a", Status: "exec"}
+	close(podmanEventsCh)
+	events := make(chan RuntimeEvent, 4)
+	translatePodmanEvents(podmanEventsCh, events)
+	close(events)
+	want := []string{EventStart, EventDie, EventDestroy, "exec"}
+	i := 0
+	for event := range events {
+		if event.ID != "a" || event.Status != want[i] {
+			t.Errorf("event %d:\ngot  %s %s\nwant %s %s", i, event.ID, event.Status, "a", want[i])
+		}
+		i++
+	}
+	if i != len(want) {
+		t.Errorf("number of events:\ngot  %d\nwant %d", i, len(want))
+	}
+}
+
+func TestPodmanContainerNames(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			w.Write([]byte(`[{"Id": "a", "Names": ["web"]}, {"Id": "b", "Names": ["/db"]}]`))
+		case strings.HasSuffix(r.URL.Path, "/containers/a/json"):
+			w.Write([]byte(`{"Id": "a", "Name": "web", "State": {"Running": true, "Pid": 42}}`))
+		case strings.HasSuffix(r.URL.Path, "/images/json"):
+			w.Write([]byte(`[]`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+	t.Setenv("PODMAN_HOST", ts.URL)
+	pr, err := NewPodmanRuntime()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	containers, err := pr.ListContainers()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(containers) != 2 || containers[0].Name != "/web" || containers[1].Name != "/db" {
+		t.Errorf("containers:\ngot  %+v", containers)
+	}
+	rc, err := pr.InspectContainer("a")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if rc.Name != "/web" || rc.PID != 42 || !rc.Running {
+		t.Errorf("container:\ngot  %+v", rc)
+	}
+}
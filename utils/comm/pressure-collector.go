@@ -0,0 +1,101 @@
+package comm
+
+import (
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+const (
+	// PressureFamily is the metric family name of Pressure Stall Information.
+	PressureFamily = "pressure"
+	// ResourceLabel is the label key of the resource, cpu, memory or io, of
+	// a pressure sample.
+	ResourceLabel = "resource"
+	// ScopeLabel is the label key of the scope, some or full, of a pressure
+	// sample.
+	ScopeLabel = "scope"
+)
+
+var pressureResources = []string{"cpu", "memory", "io"}
+
+// pressureNames are the pressure values reported, the averages are gauges in
+// hundredths of a percent and total is a counter in microseconds.
+var pressureNames = []struct {
+	name string
+	kind MetricKind
+}{
+	{"avg10", Gauge},
+	{"avg60", Gauge},
+	{"avg300", Gauge},
+	{"total", Counter},
+}
+
+// PressureCollector collects the Pressure Stall Information of containers,
+// from their cgroup in the unified hierarchy, and of the node.
+type PressureCollector struct {
+	mode u.CgroupMode
+}
+
+// NewPressureCollector returns a PressureCollector of the given layout of the
+// cgroup hierarchies.
+func NewPressureCollector(mode u.CgroupMode) *PressureCollector {
+	return &PressureCollector{mode: mode}
+}
+
+func (pc *PressureCollector) Name() string {
+	return PressureFamily
+}
+
+func (pc *PressureCollector) Discover(cont *Container) error {
+	return cont.UpdateCgroupPaths()
+}
+
+// Collect returns the cpu, memory and io pressure of the container. It is
+// only available on kernels with PSI and cgroup v2 or hybrid hierarchies, no
+// samples are returned with cgroup v1 hierarchies.
+func (pc *PressureCollector) Collect(cont *Container) ([]Sample, error) {
+	if pc.mode == u.CgroupV1 {
+		return nil, nil
+	}
+	dir := cont.UnifiedCgroupDir(pc.mode)
+	if dir == "" {
+		return nil, errNoCgroup
+	}
+	f := cont.Family(PressureFamily)
+	var samples []Sample
+	for _, resource := range pressureResources {
+		samples = append(samples, storePressure(f, cont, dir+"/"+resource+".pressure", resource)...)
+	}
+	return samples, nil
+}
+
+// CollectNode returns the cpu, memory and io pressure of the whole node.
+func (pc *PressureCollector) CollectNode(node *Node) ([]Sample, error) {
+	f := node.Family(PressureFamily)
+	var samples []Sample
+	for _, resource := range pressureResources {
+		samples = append(samples, storePressure(f, nil, u.ProcPressureDir+"/"+resource, resource)...)
+	}
+	return samples, nil
+}
+
+func storePressure(f *MetricFamily, cont *Container, path, resource string) []Sample {
+	pressure, err := u.ReadPressure(path)
+	if err != nil {
+		log.Debug("Error while reading pressure: %s", err)
+		return nil
+	}
+	var samples []Sample
+	for _, scope := range []string{"some", "full"} {
+		values, ok := pressure[scope]
+		if !ok {
+			continue
+		}
+		labels := map[string]string{ResourceLabel: resource, ScopeLabel: scope}
+		for _, pn := range pressureNames {
+			if value, ok := values[pn.name]; ok {
+				samples = append(samples, f.Store(cont, pn.name, pn.kind, labels, value))
+			}
+		}
+	}
+	return samples
+}
@@ -0,0 +1,171 @@
+package comm
+
+import (
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+const (
+	// ProcessesFamily is the metric family name of the process and file
+	// descriptor inventory of containers.
+	ProcessesFamily = "processes"
+	// PIDLabel is the label key of the PID of a process sample.
+	PIDLabel = "pid"
+	// CommandLabel is the label key of the command of a process sample.
+	CommandLabel = "command"
+)
+
+// processesScanInterval is how long a scan of all processes of the node is
+// reused between containers.
+const processesScanInterval = time.Second
+
+// ProcessesCollector collects the number of processes, threads and open file
+// descriptors of the PID namespace of containers and their top processes by
+// RSS and CPU time. It requires the host PID namespace.
+type ProcessesCollector struct {
+	mode      u.CgroupMode
+	hostPIDNS string
+	scannedAt time.Time
+	byPIDNS   map[string][]int
+}
+
+// NewProcessesCollector returns a ProcessesCollector of the given layout of
+// the cgroup hierarchies.
+func NewProcessesCollector(mode u.CgroupMode) *ProcessesCollector {
+	return &ProcessesCollector{mode: mode}
+}
+
+func (pc *ProcessesCollector) Name() string {
+	return ProcessesFamily
+}
+
+func (pc *ProcessesCollector) Discover(cont *Container) error {
+	return cont.UpdateCgroupPaths()
+}
+
+// Collect returns, as gauges, the number of processes, threads and open file
+// descriptors of the container, the soft RLIMIT_NOFILE of its main process,
+// the highest usage of RLIMIT_NOFILE by any of its processes, in hundredths of
+// a percent, and the RSS, in bytes, and CPU time, in nanoseconds, of its TopN
+// processes by each one.
+func (pc *ProcessesCollector) Collect(cont *Container) ([]Sample, error) {
+	pids, err := pc.containerPIDs(cont)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		stats                 []u.ProcessStat
+		threads, fds, fdUsage int64
+		fdLimit               int64 = math.MaxInt64
+	)
+	for _, pid := range pids {
+		ps, err := u.ReadProcessStat(pid)
+		if err != nil {
+			// The process might have exited in the meantime
+			log.Debug("%s", err)
+			continue
+		}
+		stats = append(stats, ps)
+		threads += ps.Threads
+		fds += ps.FDs
+		if pid == cont.PID {
+			fdLimit = ps.FDLimit
+		}
+		if ps.FDLimit > 0 && ps.FDLimit != math.MaxInt64 {
+			if usage := ps.FDs * 10000 / ps.FDLimit; usage > fdUsage {
+				fdUsage = usage
+			}
+		}
+	}
+
+	f := cont.Family(ProcessesFamily)
+	samples := []Sample{
+		f.Set(cont, "processes", nil, int64(len(stats))),
+		f.Set(cont, "threads", nil, threads),
+		f.Set(cont, "open_fds", nil, fds),
+		f.Set(cont, "fd_limit", nil, fdLimit),
+		f.Set(cont, "fd_limit_usage", nil, fdUsage),
+	}
+
+	f.Forget("process_rss")
+	sort.Sort(byRSS(stats))
+	for i := 0; i < len(stats) && i < TopN; i++ {
+		samples = append(samples, f.Set(cont, "process_rss", processLabels(stats[i]), stats[i].RSS))
+	}
+	f.Forget("process_cpu_time")
+	sort.Sort(byCPUTime(stats))
+	for i := 0; i < len(stats) && i < TopN; i++ {
+		samples = append(samples, f.Set(cont, "process_cpu_time", processLabels(stats[i]), stats[i].CPUTime))
+	}
+	return samples, nil
+}
+
+// containerPIDs returns the PIDs of the processes in the PID namespace of the
+// container. Containers sharing the PID namespace of the host are limited to
+// the processes of their cgroup.
+func (pc *ProcessesCollector) containerPIDs(cont *Container) ([]int, error) {
+	pidNS, err := u.ReadNamespace(cont.PID, "pid")
+	if err != nil {
+		return nil, err
+	}
+	if err := pc.scan(); err != nil {
+		return nil, err
+	}
+	if pidNS != pc.hostPIDNS {
+		return pc.byPIDNS[pidNS], nil
+	}
+	dir := cont.CgroupDir(pc.mode, "pids")
+	if dir == "" {
+		return nil, errNoCgroup
+	}
+	return u.ReadCgroupProcs(dir)
+}
+
+// scan groups all processes of the node by PID namespace, unless it was done
+// less than processesScanInterval ago.
+func (pc *ProcessesCollector) scan() error {
+	if time.Since(pc.scannedAt) < processesScanInterval {
+		return nil
+	}
+	// docker-collector runs in the host PID namespace
+	hostPIDNS, err := u.ReadNamespace(os.Getpid(), "pid")
+	if err != nil {
+		return err
+	}
+	pids, err := u.ListPIDs()
+	if err != nil {
+		return err
+	}
+	byPIDNS := map[string][]int{}
+	for _, pid := range pids {
+		if pidNS, err := u.ReadNamespace(pid, "pid"); err == nil {
+			byPIDNS[pidNS] = append(byPIDNS[pidNS], pid)
+		}
+	}
+	pc.hostPIDNS, pc.byPIDNS, pc.scannedAt = hostPIDNS, byPIDNS, time.Now()
+	return nil
+}
+
+func processLabels(ps u.ProcessStat) map[string]string {
+	return map[string]string{
+		PIDLabel:     strconv.Itoa(ps.PID),
+		CommandLabel: ps.Command,
+	}
+}
+
+type byRSS []u.ProcessStat
+
+func (b byRSS) Len() int           { return len(b) }
+func (b byRSS) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byRSS) Less(i, j int) bool { return b[i].RSS > b[j].RSS }
+
+type byCPUTime []u.ProcessStat
+
+func (b byCPUTime) Len() int           { return len(b) }
+func (b byCPUTime) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byCPUTime) Less(i, j int) bool { return b[i].CPUTime > b[j].CPUTime }
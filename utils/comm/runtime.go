@@ -0,0 +1,67 @@
+package comm
+
+import (
+	"fmt"
+)
+
+// Status of the container events sent by runtimes, named after the Docker
+// events.
+const (
+	EventStart   = "start"
+	EventStop    = "stop"
+	EventDie     = "die"
+	EventDestroy = "destroy"
+)
+
+// Runtimes are the names of all supported container runtimes.
+const Runtimes = DockerRuntimeName + "|" + PodmanRuntimeName + "|" + CRIRuntimeName
+
+// RuntimeContainer is a container as described by its container runtime.
+type RuntimeContainer struct {
+	ID      string
+	Name    string
+	PID     int
+	Running bool
+	Image   string
+	ImageID string
+	// ImageDigest is the repository digest of the image, such as
+	// "nginx@sha256:0d17b...", empty if the image has none.
+	ImageDigest string
+	Labels      map[string]string
+}
+
+// RuntimeEvent is a change of state of a container.
+type RuntimeEvent struct {
+	ID     string
+	Status string
+}
+
+// Runtime lists, inspects and monitors the containers of a container runtime.
+type Runtime interface {
+	// Name returns the name of the runtime.
+	Name() string
+	// Ping returns an error if the runtime can't be reached.
+	Ping() error
+	// ListContainers returns all containers, running or not. Only the ID
+	// and the Name of the returned containers are set.
+	ListContainers() ([]RuntimeContainer, error)
+	// InspectContainer returns the container with the given ID.
+	InspectContainer(id string) (*RuntimeContainer, error)
+	// MonitorEvents sends the events of all containers to the given
+	// channel, in the background.
+	MonitorEvents(events chan<- RuntimeEvent) error
+}
+
+// NewRuntimeOf returns a client of the container runtime with the given name.
+func NewRuntimeOf(name string) (Runtime, error) {
+	switch name {
+	case DockerRuntimeName:
+		return NewDockerRuntime()
+	case PodmanRuntimeName:
+		return NewPodmanRuntime()
+	case CRIRuntimeName:
+		return NewCRIRuntime()
+	default:
+		return nil, fmt.Errorf("invalid runtime '%s'", name)
+	}
+}
@@ -0,0 +1,94 @@
+package comm
+
+import (
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+const (
+	// SNMPFamily is the metric family name of the protocol counters of the
+	// network namespace of containers.
+	SNMPFamily = "snmp"
+	// ProtocolLabel is the label key of the protocol of a sample.
+	ProtocolLabel = "protocol"
+)
+
+// snmpCounters are the counters reported from /proc/<pid>/net/snmp and
+// /proc/<pid>/net/netstat.
+var snmpCounters = []struct {
+	file     string
+	protocol string
+	names    []string
+}{
+	{"snmp", "Tcp", []string{"RetransSegs", "ActiveOpens", "PassiveOpens", "AttemptFails", "EstabResets", "InErrs"}},
+	{"snmp", "Udp", []string{"InErrors", "RcvbufErrors", "SndbufErrors"}},
+	{"netstat", "TcpExt", []string{"ListenOverflows", "ListenDrops", "TCPTimeouts"}},
+}
+
+// snmp6Counters are the IPv6 counters reported from /proc/<pid>/net/snmp6,
+// where names are prefixed by the protocol. TCP counters are shared by IPv4
+// and IPv6.
+var snmp6Counters = []struct {
+	protocol string
+	names    []string
+}{
+	{"Udp6", []string{"InErrors", "RcvbufErrors", "SndbufErrors"}},
+}
+
+// SNMPCollector collects the TCP and UDP counters of the network namespace of
+// containers.
+type SNMPCollector struct{}
+
+func NewSNMPCollector() *SNMPCollector {
+	return &SNMPCollector{}
+}
+
+func (sc *SNMPCollector) Name() string {
+	return SNMPFamily
+}
+
+func (sc *SNMPCollector) netNSScoped() {}
+
+func (sc *SNMPCollector) Discover(cont *Container) error {
+	return nil
+}
+
+// Collect returns the difference since the last collection of the TCP and UDP
+// counters of the container. IPv6 counters are reported with the protocol
+// label suffixed by 6, such as Udp6.
+func (sc *SNMPCollector) Collect(cont *Container) ([]Sample, error) {
+	f := cont.Family(SNMPFamily)
+	var samples []Sample
+	files := map[string]map[string]map[string]int64{}
+	for _, counters := range snmpCounters {
+		snmp, ok := files[counters.file]
+		if !ok {
+			var err error
+			if snmp, err = u.ReadNetSNMP(cont.PID, counters.file); err != nil {
+				return nil, err
+			}
+			files[counters.file] = snmp
+		}
+		labels := map[string]string{ProtocolLabel: counters.protocol}
+		for _, name := range counters.names {
+			if value, ok := snmp[counters.protocol][name]; ok {
+				samples = append(samples, f.Update(cont, name, labels, value))
+			}
+		}
+	}
+
+	snmp6, err := u.ReadNetSNMP6(cont.PID)
+	if err != nil {
+		// IPv6 might be disabled in the container
+		log.Debug("Error while reading IPv6 counters of %s: %s", cont.DockerID, err)
+		return samples, nil
+	}
+	for _, counters := range snmp6Counters {
+		labels := map[string]string{ProtocolLabel: counters.protocol}
+		for _, name := range counters.names {
+			if value, ok := snmp6[counters.protocol+name]; ok {
+				samples = append(samples, f.Update(cont, name, labels, value))
+			}
+		}
+	}
+	return samples, nil
+}
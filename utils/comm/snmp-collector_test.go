@@ -0,0 +1,32 @@
+package comm
+
+import (
+	"os"
+	"testing"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+func TestSNMPCollectorCollect(t *testing.T) {
+	pid := os.Getpid()
+	if _, err := os.Stat(u.ProcPath(pid, "/net/snmp6")); err != nil {
+		t.Skipf("IPv6 counters not available: %s", err)
+	}
+	cont := &Container{DockerID: "4b825dc642cb", PID: pid}
+	samples, err := NewSNMPCollector().Collect(cont)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	protocols := map[string]int{}
+	for _, s := range samples {
+		protocols[s.Labels[ProtocolLabel]]++
+		if s.Value != 0 {
+			t.Errorf("first read of %s %s:\ngot  %d\nwant %d", s.Labels[ProtocolLabel], s.Name, s.Value, 0)
+		}
+	}
+	for protocol, want := range map[string]int{"Tcp": 6, "Udp": 3, "Udp6": 3} {
+		if protocols[protocol] != want {
+			t.Errorf("samples of %s:\ngot  %d\nwant %d", protocol, protocols[protocol], want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package comm
+
+import (
+	"sort"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+const (
+	// SocketsFamily is the metric family name of the socket census of the
+	// network namespace of containers.
+	SocketsFamily = "sockets"
+	// StateLabel is the label key of the TCP state of a sample.
+	StateLabel = "state"
+	// PeerLabel is the label key of the remote address of a sample.
+	PeerLabel = "peer"
+)
+
+const tcpListen = 0x0A
+
+// tcpStates are the names of the TCP states as stored in the socket tables.
+var tcpStates = []string{
+	1:  "ESTABLISHED",
+	2:  "SYN_SENT",
+	3:  "SYN_RECV",
+	4:  "FIN_WAIT1",
+	5:  "FIN_WAIT2",
+	6:  "TIME_WAIT",
+	7:  "CLOSE",
+	8:  "CLOSE_WAIT",
+	9:  "LAST_ACK",
+	10: "LISTEN",
+	11: "CLOSING",
+}
+
+// TopN is the number of entries reported by collectors that report the top
+// entries of a container, such as the remote peers with most connections.
+var TopN = 5
+
+// SocketsCollector collects the number of sockets of the network namespace of
+// containers per TCP state, the number of listening ports and the remote
+// peers with most connections.
+type SocketsCollector struct{}
+
+func NewSocketsCollector() *SocketsCollector {
+	return &SocketsCollector{}
+}
+
+func (sc *SocketsCollector) Name() string {
+	return SocketsFamily
+}
+
+func (sc *SocketsCollector) netNSScoped() {}
+
+func (sc *SocketsCollector) Discover(cont *Container) error {
+	return nil
+}
+
+// Collect returns, as gauges, the number of TCP sockets in each state, the
+// number of UDP and unix sockets, the number of TCP listening ports and the
+// number of TCP connections of the TopN remote peers of the container.
+// Only connected sockets, see u.Socket.Connected, are counted as connections
+// of peers.
+func (sc *SocketsCollector) Collect(cont *Container) ([]Sample, error) {
+	var tcp, udp []u.Socket
+	for _, file := range []string{"tcp", "tcp6"} {
+		sockets, err := u.ReadNetSockets(cont.PID, file)
+		if err != nil {
+			if file == "tcp" {
+				return nil, err
+			}
+			log.Debug("%s", err)
+		}
+		tcp = append(tcp, sockets...)
+	}
+	for _, file := range []string{"udp", "udp6"} {
+		sockets, err := u.ReadNetSockets(cont.PID, file)
+		if err != nil {
+			log.Debug("%s", err)
+		}
+		udp = append(udp, sockets...)
+	}
+
+	f := cont.Family(SocketsFamily)
+	var samples []Sample
+
+	states := make([]int64, len(tcpStates))
+	listening := map[int]bool{}
+	peers := map[string]int64{}
+	for _, sock := range tcp {
+		if sock.State > 0 && sock.State < len(tcpStates) {
+			states[sock.State]++
+		}
+		if sock.State == tcpListen {
+			listening[sock.LocalPort] = true
+		} else if sock.Connected() {
+			peers[sock.RemoteIP.String()]++
+		}
+	}
+	for state := 1; state < len(tcpStates); state++ {
+		labels := map[string]string{StateLabel: tcpStates[state]}
+		samples = append(samples, f.Set(cont, "tcp", labels, states[state]))
+	}
+	samples = append(samples, f.Set(cont, "listening_ports", nil, int64(len(listening))))
+	samples = append(samples, f.Set(cont, "udp", nil, int64(len(udp))))
+	if unix, err := u.CountNetUnixSockets(cont.PID); err == nil {
+		samples = append(samples, f.Set(cont, "unix", nil, int64(unix)))
+	} else {
+		log.Debug("%s", err)
+	}
+
+	f.Forget("peer_connections")
+	for _, peer := range topPeers(peers, TopN) {
+		labels := map[string]string{PeerLabel: peer}
+		samples = append(samples, f.Set(cont, "peer_connections", labels, peers[peer]))
+	}
+	return samples, nil
+}
+
+// topPeers returns the n peers with most connections.
+func topPeers(peers map[string]int64, n int) []string {
+	names := make([]string, 0, len(peers))
+	for peer := range peers {
+		names = append(names, peer)
+	}
+	sort.Sort(byConnections{names, peers})
+	if len(names) > n {
+		names = names[:n]
+	}
+	return names
+}
+
+type byConnections struct {
+	names []string
+	peers map[string]int64
+}
+
+func (b byConnections) Len() int      { return len(b.names) }
+func (b byConnections) Swap(i, j int) { b.names[i], b.names[j] = b.names[j], b.names[i] }
+func (b byConnections) Less(i, j int) bool {
+	ci, cj := b.peers[b.names[i]], b.peers[b.names[j]]
+	if ci != cj {
+		return ci > cj
+	}
+	return b.names[i] < b.names[j]
+}
@@ -0,0 +1,73 @@
+package comm
+
+import (
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+// SystemFamily is the metric family name of the load, memory and uptime of
+// the node.
+const SystemFamily = "system"
+
+// memInfoKeys are the /proc/meminfo values reported, in bytes.
+var memInfoKeys = []struct {
+	key  string
+	name string
+}{
+	{"MemTotal", "mem_total"},
+	{"MemFree", "mem_free"},
+	{"MemAvailable", "mem_available"},
+	{"Buffers", "buffers"},
+	{"Cached", "cached"},
+	{"SwapTotal", "swap_total"},
+	{"SwapFree", "swap_free"},
+}
+
+// SystemCollector collects the load average, memory and uptime of the node.
+// It doesn't read any metric of containers.
+type SystemCollector struct{}
+
+func NewSystemCollector() *SystemCollector {
+	return &SystemCollector{}
+}
+
+func (sc *SystemCollector) Name() string {
+	return SystemFamily
+}
+
+func (sc *SystemCollector) Discover(cont *Container) error {
+	return nil
+}
+
+func (sc *SystemCollector) Collect(cont *Container) ([]Sample, error) {
+	return nil, nil
+}
+
+// CollectNode returns the load averages, in hundredths, the number of
+// runnable and existing tasks, the memory and swap usage and the uptime, in
+// seconds, of the node.
+func (sc *SystemCollector) CollectNode(node *Node) ([]Sample, error) {
+	f := node.Family(SystemFamily)
+	var samples []Sample
+	loadAvg, err := u.ReadLoadAvg()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range []string{"load1", "load5", "load15", "procs_running", "procs_total"} {
+		samples = append(samples, f.Set(nil, name, nil, loadAvg[name]))
+	}
+	memInfo, err := u.ReadMemInfo()
+	if err != nil {
+		return nil, err
+	}
+	for _, mk := range memInfoKeys {
+		if value, ok := memInfo[mk.key]; ok {
+			samples = append(samples, f.Set(nil, mk.name, nil, value))
+		}
+	}
+	uptime, err := u.ReadUptime()
+	if err != nil {
+		return nil, err
+	}
+	samples = append(samples, f.Set(nil, "uptime", nil, uptime))
+	return samples, nil
+}
@@ -0,0 +1,111 @@
+package comm
+
+import (
+	"fmt"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+// TopologyLink connects a network interface of a container to the interface
+// of the host it is linked to and to the bridge that interface is attached
+// to.
+type TopologyLink struct {
+	ContainerDockerID string
+	ContainerName     string
+	Interface         string
+	HostInterface     string
+	Bridge            string
+	BridgeType        string
+}
+
+// UpdateHostLinks reads the network interfaces of the host network namespace
+// once and shares them with the containers of the node, which map their
+// interfaces to them until the next update.
+func (n *Node) UpdateHostLinks() error {
+	log.Debug("")
+	links, err := readNetLinks(u.HostPID)
+	n.HostLinks = links
+	for i := range n.Containers {
+		n.Containers[i].hostLinks = links
+	}
+	return err
+}
+
+// UpdateHostInterfaces sets the host interface and the bridge of each network
+// interface of the container linked to an interface of the host, such as a
+// veth, using the host interfaces last read by the node. Containers using the
+// host network namespace are left untouched.
+func (cont *Container) UpdateHostInterfaces() error {
+	log.Debug("")
+	if cont.hostLinks == nil {
+		return fmt.Errorf("host interfaces of '%s' not read", cont.Name)
+	}
+	contNS, err := u.ReadNamespace(cont.PID, "net")
+	if err != nil {
+		return err
+	}
+	hostNS, err := u.ReadNamespace(u.HostPID, "net")
+	if err != nil {
+		return err
+	}
+	if contNS == hostNS {
+		return nil
+	}
+	mapHostInterfaces(cont.NetworkInterfaces, cont.hostLinks)
+	return nil
+}
+
+// mapHostInterfaces finds, for each of the given network interfaces, the host
+// interface with the index the interface is linked to. A veth is only mapped
+// to a host interface linked back to it, since indexes are only unique inside
+// a network namespace.
+func mapHostInterfaces(netInters []NetworkInterface, hostLinks []u.NetLink) {
+	byIndex := make(map[int]*u.NetLink, len(hostLinks))
+	for i := range hostLinks {
+		byIndex[hostLinks[i].Index] = &hostLinks[i]
+	}
+	for i := range netInters {
+		netInter := &netInters[i]
+		netInter.HostInterface, netInter.Bridge, netInter.BridgeType = "", "", ""
+		if netInter.ParentIndex == 0 || netInter.ParentIndex == netInter.Index {
+			continue
+		}
+		hostLink, ok := byIndex[netInter.ParentIndex]
+		if !ok {
+			continue
+		}
+		if netInter.Type == "veth" && hostLink.ParentIndex != netInter.Index {
+			continue
+		}
+		netInter.HostInterface = hostLink.Name
+		if master, ok := byIndex[hostLink.MasterIndex]; ok {
+			netInter.Bridge = master.Name
+			netInter.BridgeType = master.Kind
+		}
+	}
+}
+
+// Topology returns the links between the active network interfaces of the
+// active containers and the host interfaces.
+func (n *Node) Topology() []TopologyLink {
+	var links []TopologyLink
+	for _, cont := range n.Containers {
+		if !cont.IsActive {
+			continue
+		}
+		for _, netInter := range cont.NetworkInterfaces {
+			if !netInter.IsActive || netInter.HostInterface == "" {
+				continue
+			}
+			links = append(links, TopologyLink{
+				ContainerDockerID: cont.DockerID,
+				ContainerName:     cont.NodeName + cont.Name,
+				Interface:         netInter.Name,
+				HostInterface:     netInter.HostInterface,
+				Bridge:            netInter.Bridge,
+				BridgeType:        netInter.BridgeType,
+			})
+		}
+	}
+	return links
+}
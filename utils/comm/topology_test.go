@@ -0,0 +1,40 @@
+package comm
+
+import (
+	"testing"
+
+	u "github.com/cilium-team/docker-collector/utils"
+)
+
+func TestMapHostInterfaces(t *testing.T) {
+	hostLinks := []u.NetLink{
+		{Index: 1, Name: "lo"},
+		{Index: 2, Name: "eth0"},
+		{Index: 3, Name: "docker0", Kind: "bridge"},
+		{Index: 7, Name: "vethab12", Kind: "veth", ParentIndex: 5, MasterIndex: 3},
+		{Index: 8, Name: "vethcd34", Kind: "veth", ParentIndex: 9, MasterIndex: 3},
+	}
+	tests := []struct {
+		name     string
+		netInter NetworkInterface
+		host     string
+		bridge   string
+		bridgeTy string
+	}{
+		{"veth peer", NetworkInterface{Name: "eth0", Type: "veth", Index: 5, ParentIndex: 7}, "vethab12", "docker0", "bridge"},
+		{"veth peer of another namespace", NetworkInterface{Name: "eth0", Type: "veth", Index: 6, ParentIndex: 8}, "", "", ""},
+		{"macvlan parent", NetworkInterface{Name: "eth1", Type: "macvlan", Index: 4, ParentIndex: 2}, "eth0", "", ""},
+		{"missing index", NetworkInterface{Name: "eth2", Type: "veth", Index: 10, ParentIndex: 42}, "", "", ""},
+		{"not linked", NetworkInterface{Name: "lo", Index: 1}, "", "", ""},
+		{"stale mapping", NetworkInterface{Name: "eth3", Index: 11, HostInterface: "old", Bridge: "br0", BridgeType: "bridge"}, "", "", ""},
+	}
+	for _, tt := range tests {
+		netInters := []NetworkInterface{tt.netInter}
+		mapHostInterfaces(netInters, hostLinks)
+		got := netInters[0]
+		if got.HostInterface != tt.host || got.Bridge != tt.bridge || got.BridgeType != tt.bridgeTy {
+			t.Errorf("%s:\ngot  %q %q %q\nwant %q %q %q", tt.name,
+				got.HostInterface, got.Bridge, got.BridgeType, tt.host, tt.bridge, tt.bridgeTy)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"encoding/binary"
+	"errors"
+	"unsafe"
+)
+
+// ErrNotSupported is returned when a statistic source is not available on
+// the running platform.
+var ErrNotSupported = errors.New("not supported on this platform")
+
+// nativeEndian is the byte order of the running platform, used by the kernel
+// in netlink messages and in /proc socket tables.
+var nativeEndian = func() binary.ByteOrder {
+	x := uint16(1)
+	if *(*byte)(unsafe.Pointer(&x)) == 1 {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}()
+
+// NetLink holds the attributes and the statistics of a network interface.
+type NetLink struct {
+	Index int
+	Name  string
+	// Kind is the type of virtual interface, such as veth, macvlan, ipvlan
+	// or vxlan, empty for physical interfaces.
+	Kind string
+	// OperState is the RFC 2863 operational state, such as up or down.
+	OperState  string
+	Carrier    bool
+	MTU        int
+	MACAddress string
+	// Speed is the link speed in Mbit/s, 0 if unknown.
+	Speed int64
+	// Addresses are the IPv4 and IPv6 addresses in CIDR notation.
+	Addresses []string
+	// ParentIndex is the index of the interface this interface is linked
+	// to, such as the peer of a veth, which might be in another namespace.
+	ParentIndex int
+	// MasterIndex is the index of the bridge, or OVS datapath, this
+	// interface is attached to, 0 if none.
+	MasterIndex int
+	Stats       map[string]int64
+}
+
+// operStates are the names of the RFC 2863 operational states.
+var operStates = []string{
+	"unknown",
+	"notpresent",
+	"down",
+	"lowerlayerdown",
+	"testing",
+	"dormant",
+	"up",
+}
+
+func operStateName(state byte) string {
+	if int(state) < len(operStates) {
+		return operStates[state]
+	}
+	return operStates[0]
+}
+
+// linkStatsNames are the sysfs statistic names of each field of the
+// rtnl_link_stats64 structure, in the same order as they are stored by the
+// kernel.
+var linkStatsNames = []string{
+	"rx_packets",
+	"tx_packets",
+	"rx_bytes",
+	"tx_bytes",
+	"rx_errors",
+	"tx_errors",
+	"rx_dropped",
+	"tx_dropped",
+	"multicast",
+	"collisions",
+	"rx_length_errors",
+	"rx_over_errors",
+	"rx_crc_errors",
+	"rx_frame_errors",
+	"rx_fifo_errors",
+	"rx_missed_errors",
+	"tx_aborted_errors",
+	"tx_carrier_errors",
+	"tx_fifo_errors",
+	"tx_heartbeat_errors",
+	"tx_window_errors",
+	"rx_compressed",
+	"tx_compressed",
+	"rx_nohandler",
+}
@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	iflaCarrier   = 0x21
+	iflaStats64   = 0x17
+	iflaInfoKind  = 0x1
+	nlaHeaderLen  = 4
+	nlaTypeMask   = 0x3fff
+	nlaAlignBytes = 4
+	siocEthtool   = 0x8946
+	ethtoolGSet   = 0x1
+	// ethtoolSpeedUnknown is the speed reported by interfaces without link.
+	ethtoolSpeedUnknown = 0xffffffff
+)
+
+// ethtoolCmd is the struct ethtool_cmd of the ETHTOOL_GSET command.
+type ethtoolCmd struct {
+	Cmd           uint32
+	Supported     uint32
+	Advertising   uint32
+	Speed         uint16
+	Duplex        uint8
+	Port          uint8
+	PhyAddress    uint8
+	Transceiver   uint8
+	Autoneg       uint8
+	MdioSupport   uint8
+	Maxtxpkt      uint32
+	Maxrxpkt      uint32
+	SpeedHi       uint16
+	EthTpMdix     uint8
+	EthTpMdixCtrl uint8
+	LpAdvertising uint32
+	Reserved      [2]uint32
+}
+
+// ifreqData is the struct ifreq of ioctls whose argument is a pointer. Data
+// is an unsafe.Pointer, not an uintptr, so that the argument is kept alive
+// by the ifreq during the ioctl.
+type ifreqData struct {
+	Name [syscall.IFNAMSIZ]byte
+	Data unsafe.Pointer
+	_    [24 - unsafe.Sizeof(uintptr(0))]byte
+}
+
+// ReadNetLinks returns the network interfaces, with their attributes,
+// addresses, speed and statistics, of the network namespace of the process
+// with the given pid. All interfaces are retrieved with a single RTM_GETLINK
+// netlink dump and all addresses with a single RTM_GETADDR netlink dump. The
+// speed of each interface is read with ethtool.
+func ReadNetLinks(pid int) ([]NetLink, error) {
+	var links []NetLink
+	err := WithNetNS(pid, func() (err error) {
+		links, err = dumpNetLinksAndAddrs()
+		return
+	})
+	return links, err
+}
+
+// ReadNetLinksPath returns the network interfaces of the network namespace of
+// the given file the same way as ReadNetLinks.
+func ReadNetLinksPath(nsPath string) ([]NetLink, error) {
+	var links []NetLink
+	err := WithNetNSPath(nsPath, func() (err error) {
+		links, err = dumpNetLinksAndAddrs()
+		return
+	})
+	return links, err
+}
+
+func dumpNetLinksAndAddrs() ([]NetLink, error) {
+	links, err := dumpNetLinks()
+	if err != nil {
+		return nil, err
+	}
+	readEthtoolSpeeds(links)
+	return links, dumpNetAddrs(links)
+}
+
+// readEthtoolSpeeds sets the speed of the given links, in the current network
+// namespace, if their driver reports it.
+func readEthtoolSpeeds(links []NetLink) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		log.Debug("Unable to read interface speeds: %s", err)
+		return
+	}
+	defer syscall.Close(fd)
+	for i := range links {
+		links[i].Speed = ethtoolSpeed(fd, links[i].Name)
+	}
+}
+
+// ethtoolSpeed returns the speed, in Mbit/s, of the interface with the given
+// name or 0 if it doesn't report it, such as the loopback interface.
+func ethtoolSpeed(fd int, name string) int64 {
+	if len(name) >= syscall.IFNAMSIZ {
+		return 0
+	}
+	cmd := &ethtoolCmd{Cmd: ethtoolGSet}
+	ifr := &ifreqData{Data: unsafe.Pointer(cmd)}
+	copy(ifr.Name[:], name)
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, uintptr(fd), siocEthtool, uintptr(unsafe.Pointer(ifr)))
+	if errno != 0 {
+		return 0
+	}
+	speed := uint32(cmd.SpeedHi)<<16 | uint32(cmd.Speed)
+	if speed == ethtoolSpeedUnknown || speed == ethtoolSpeedUnknown>>16 {
+		return 0
+	}
+	return int64(speed)
+}
+
+func dumpNetLinks() ([]NetLink, error) {
+	msgs, err := netlinkDump(syscall.RTM_GETLINK)
+	if err != nil {
+		return nil, err
+	}
+	var links []NetLink
+	for i := range msgs {
+		if msgs[i].Header.Type != syscall.RTM_NEWLINK ||
+			len(msgs[i].Data) < syscall.SizeofIfInfomsg {
+			continue
+		}
+		link, err := parseNetLink(&msgs[i])
+		if err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+	return links, nil
+}
+
+// dumpNetAddrs adds the IPv4 and IPv6 addresses to each of the given links.
+func dumpNetAddrs(links []NetLink) error {
+	msgs, err := netlinkDump(syscall.RTM_GETADDR)
+	if err != nil {
+		return err
+	}
+	for i := range msgs {
+		if msgs[i].Header.Type != syscall.RTM_NEWADDR ||
+			len(msgs[i].Data) < syscall.SizeofIfAddrmsg {
+			continue
+		}
+		ifam := (*syscall.IfAddrmsg)(unsafe.Pointer(&msgs[i].Data[0]))
+		attrs, err := syscall.ParseNetlinkRouteAttr(&msgs[i])
+		if err != nil {
+			return os.NewSyscallError("parsenetlinkrouteattr", err)
+		}
+		var ip net.IP
+		for _, a := range attrs {
+			switch a.Attr.Type {
+			case syscall.IFA_LOCAL:
+				ip = net.IP(a.Value)
+			case syscall.IFA_ADDRESS:
+				if ip == nil {
+					ip = net.IP(a.Value)
+				}
+			}
+		}
+		if ip == nil {
+			continue
+		}
+		for j := range links {
+			if links[j].Index == int(ifam.Index) {
+				addr := ip.String() + "/" + strconv.Itoa(int(ifam.Prefixlen))
+				links[j].Addresses = append(links[j].Addresses, addr)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+func netlinkDump(proto int) ([]syscall.NetlinkMessage, error) {
+	rib, err := syscall.NetlinkRIB(proto, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, os.NewSyscallError("netlinkrib", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, os.NewSyscallError("parsenetlinkmessage", err)
+	}
+	return msgs, nil
+}
+
+func parseNetLink(m *syscall.NetlinkMessage) (NetLink, error) {
+	ifim := (*syscall.IfInfomsg)(unsafe.Pointer(&m.Data[0]))
+	link := NetLink{Index: int(ifim.Index)}
+	attrs, err := syscall.ParseNetlinkRouteAttr(m)
+	if err != nil {
+		return link, os.NewSyscallError("parsenetlinkrouteattr", err)
+	}
+	var stats32 []byte
+	for _, a := range attrs {
+		switch a.Attr.Type {
+		case syscall.IFLA_IFNAME:
+			link.Name = string(trimNull(a.Value))
+		case syscall.IFLA_ADDRESS:
+			link.MACAddress = net.HardwareAddr(a.Value).String()
+		case syscall.IFLA_MTU:
+			if len(a.Value) >= 4 {
+				link.MTU = int(nativeEndian.Uint32(a.Value))
+			}
+		case syscall.IFLA_LINK:
+			if len(a.Value) >= 4 {
+				link.ParentIndex = int(nativeEndian.Uint32(a.Value))
+			}
+		case syscall.IFLA_MASTER:
+			if len(a.Value) >= 4 {
+				link.MasterIndex = int(nativeEndian.Uint32(a.Value))
+			}
+		case syscall.IFLA_OPERSTATE:
+			if len(a.Value) >= 1 {
+				link.OperState = operStateName(a.Value[0])
+			}
+		case iflaCarrier:
+			if len(a.Value) >= 1 {
+				link.Carrier = a.Value[0] == 1
+			}
+		case syscall.IFLA_LINKINFO:
+			for _, info := range parseNestedAttrs(a.Value) {
+				if info.Attr.Type == iflaInfoKind {
+					link.Kind = string(trimNull(info.Value))
+				}
+			}
+		case iflaStats64:
+			link.Stats = parseLinkStats(a.Value, 8)
+		case syscall.IFLA_STATS:
+			stats32 = a.Value
+		}
+	}
+	if link.Stats == nil && stats32 != nil {
+		link.Stats = parseLinkStats(stats32, 4)
+	}
+	return link, nil
+}
+
+// parseNestedAttrs parses the attributes nested in a netlink attribute.
+func parseNestedAttrs(b []byte) []syscall.NetlinkRouteAttr {
+	var attrs []syscall.NetlinkRouteAttr
+	for len(b) >= nlaHeaderLen {
+		l := int(nativeEndian.Uint16(b))
+		if l < nlaHeaderLen || l > len(b) {
+			break
+		}
+		attr := syscall.NetlinkRouteAttr{
+			Value: b[nlaHeaderLen:l],
+		}
+		attr.Attr.Len = uint16(l)
+		attr.Attr.Type = nativeEndian.Uint16(b[2:]) & nlaTypeMask
+		attrs = append(attrs, attr)
+		l = (l + nlaAlignBytes - 1) &^ (nlaAlignBytes - 1)
+		if l > len(b) {
+			break
+		}
+		b = b[l:]
+	}
+	return attrs
+}
+
+// parseLinkStats parses a rtnl_link_stats, or rtnl_link_stats64 if size is 8,
+// structure.
+func parseLinkStats(b []byte, size int) map[string]int64 {
+	stats := make(map[string]int64, len(linkStatsNames))
+	for i, name := range linkStatsNames {
+		if (i+1)*size > len(b) {
+			break
+		}
+		if size == 8 {
+			stats[name] = int64(nativeEndian.Uint64(b[i*size:]))
+		} else {
+			stats[name] = int64(nativeEndian.Uint32(b[i*size:]))
+		}
+	}
+	return stats
+}
+
+func trimNull(b []byte) []byte {
+	for i, c := range b {
+		if c == 0 {
+			return b[:i]
+		}
+	}
+	return b
+}
@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"syscall"
+	"testing"
+)
+
+// linkStats64 returns a rtnl_link_stats64 structure whose fields are set to
+// their index plus one, followed by extra fields unknown to linkStatsNames.
+func linkStats64(extra int) []byte {
+	b := make([]byte, (len(linkStatsNames)+extra)*8)
+	for i := 0; i < len(linkStatsNames)+extra; i++ {
+		nativeEndian.PutUint64(b[i*8:], uint64(i+1))
+	}
+	return b
+}
+
+func TestParseLinkStats(t *testing.T) {
+	b := linkStats64(2)
+	// A counter over 32 bits, only fits in rtnl_link_stats64.
+	nativeEndian.PutUint64(b[2*8:], 1<<40)
+	stats := parseLinkStats(b, 8)
+	if len(stats) != len(linkStatsNames) {
+		t.Errorf("invalid number of stats:\ngot  %d\nwant %d", len(stats), len(linkStatsNames))
+	}
+	want := map[string]int64{
+		"rx_packets":   1,
+		"tx_packets":   2,
+		"rx_bytes":     1 << 40,
+		"tx_bytes":     4,
+		"rx_dropped":   7,
+		"rx_nohandler": int64(len(linkStatsNames)),
+	}
+	for key, value := range want {
+		if got := stats[key]; got != value {
+			t.Errorf("%s:\ngot  %d\nwant %d", key, got, value)
+		}
+	}
+
+	// Older kernels send shorter structures.
+	b = make([]byte, 3*4+2)
+	for i := 0; i < 3; i++ {
+		nativeEndian.PutUint32(b[i*4:], uint32(10*(i+1)))
+	}
+	stats = parseLinkStats(b, 4)
+	if len(stats) != 3 || stats["rx_packets"] != 10 || stats["rx_bytes"] != 30 {
+		t.Errorf("invalid rtnl_link_stats:\ngot  %v\nwant %s", stats, "rx_packets:10 tx_packets:20 rx_bytes:30")
+	}
+}
+
+// nlAttr returns a netlink attribute, padded to 4 bytes.
+func nlAttr(typ uint16, value []byte) []byte {
+	b := make([]byte, nlaHeaderLen, nlaHeaderLen+len(value)+nlaAlignBytes)
+	nativeEndian.PutUint16(b, uint16(nlaHeaderLen+len(value)))
+	nativeEndian.PutUint16(b[2:], typ)
+	b = append(b, value...)
+	for len(b)%nlaAlignBytes != 0 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func nlUint32(v uint32) []byte {
+	b := make([]byte, 4)
+	nativeEndian.PutUint32(b, v)
+	return b
+}
+
+// nlLinkMessage returns a RTM_NEWLINK message of the interface with the given
+// index and attributes.
+func nlLinkMessage(index int32, attrs ...[]byte) *syscall.NetlinkMessage {
+	data := make([]byte, syscall.SizeofIfInfomsg)
+	nativeEndian.PutUint32(data[4:], uint32(index))
+	for _, attr := range attrs {
+		data = append(data, attr...)
+	}
+	m := &syscall.NetlinkMessage{Data: data}
+	m.Header.Type = syscall.RTM_NEWLINK
+	m.Header.Len = uint32(syscall.NLMSG_HDRLEN + len(data))
+	return m
+}
+
+func TestParseNestedAttrs(t *testing.T) {
+	const nlaFNested = 0x8000
+	b := append(nlAttr(iflaInfoKind, []byte("vxlan\x00")), nlAttr(2|nlaFNested, nlUint32(42))...)
+	// A truncated attribute ends the parsing.
+	b = append(b, 0xff, 0x00, 0x01, 0x00)
+	attrs := parseNestedAttrs(b)
+	if len(attrs) != 2 {
+		t.Fatalf("number of attributes:\ngot  %d\nwant %d", len(attrs), 2)
+	}
+	if attrs[0].Attr.Type != iflaInfoKind || string(trimNull(attrs[0].Value)) != "vxlan" {
+		t.Errorf("first attribute:\ngot  %d %q\nwant %d %q", attrs[0].Attr.Type, attrs[0].Value, iflaInfoKind, "vxlan")
+	}
+	if attrs[1].Attr.Type != 2 || nativeEndian.Uint32(attrs[1].Value) != 42 {
+		t.Errorf("second attribute:\ngot  %d %v\nwant %d %d", attrs[1].Attr.Type, attrs[1].Value, 2, 42)
+	}
+	if attrs := parseNestedAttrs([]byte{1, 0}); len(attrs) != 0 {
+		t.Errorf("attributes of a short buffer:\ngot  %v\nwant none", attrs)
+	}
+}
+
+func TestParseNetLink(t *testing.T) {
+	linkInfo := append(nlAttr(iflaInfoKind, []byte("veth\x00")), nlAttr(2, []byte{1, 2, 3, 4})...)
+	stats32 := make([]byte, len(linkStatsNames)*4)
+	nativeEndian.PutUint32(stats32[2*4:], 99)
+	m := nlLinkMessage(5,
+		nlAttr(syscall.IFLA_IFNAME, []byte("eth0\x00")),
+		nlAttr(syscall.IFLA_ADDRESS, []byte{0x02, 0x42, 0xac, 0x11, 0x00, 0x02}),
+		nlAttr(syscall.IFLA_MTU, nlUint32(1450)),
+		nlAttr(syscall.IFLA_LINK, nlUint32(12)),
+		nlAttr(syscall.IFLA_MASTER, nlUint32(3)),
+		nlAttr(syscall.IFLA_OPERSTATE, []byte{6}),
+		nlAttr(iflaCarrier, []byte{1}),
+		nlAttr(syscall.IFLA_LINKINFO, linkInfo),
+		nlAttr(syscall.IFLA_STATS, stats32),
+		nlAttr(iflaStats64, linkStats64(0)),
+	)
+	link, err := parseNetLink(m)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if link.Index != 5 || link.Name != "eth0" || link.Kind != "veth" || link.MTU != 1450 ||
+		link.MACAddress != "02:42:ac:11:00:02" || link.ParentIndex != 12 || link.MasterIndex != 3 ||
+		link.OperState != "up" || !link.Carrier {
+		t.Errorf("unexpected link: %+v", link)
+	}
+	// rtnl_link_stats64 is preferred to rtnl_link_stats.
+	if link.Stats["rx_bytes"] != 3 || link.Stats["rx_nohandler"] != int64(len(linkStatsNames)) {
+		t.Errorf("unexpected statistics: %v", link.Stats)
+	}
+
+	m = nlLinkMessage(1,
+		nlAttr(syscall.IFLA_IFNAME, []byte("lo\x00")),
+		nlAttr(syscall.IFLA_OPERSTATE, []byte{0}),
+		nlAttr(syscall.IFLA_STATS, stats32),
+	)
+	if link, err = parseNetLink(m); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if link.Name != "lo" || link.Kind != "" || link.OperState != "unknown" || link.Carrier || link.Stats["rx_bytes"] != 99 {
+		t.Errorf("unexpected link without rtnl_link_stats64: %+v", link)
+	}
+}
+
+func TestEthtoolSpeed(t *testing.T) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_DGRAM, 0)
+	if err != nil {
+		t.Skipf("unable to open socket: %s", err)
+	}
+	defer syscall.Close(fd)
+	// The loopback interface has no speed and names too long are ignored.
+	for _, name := range []string{"lo", "an-interface-name-too-long"} {
+		if speed := ethtoolSpeed(fd, name); speed != 0 {
+			t.Errorf("speed of %s:\ngot  %d\nwant %d", name, speed, 0)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+// +build !linux
+
+package utils
+
+// ReadNetLinks is only supported on linux.
+func ReadNetLinks(pid int) ([]NetLink, error) {
+	return nil, ErrNotSupported
+}
+
+// WithNetNS is only supported on linux.
+func WithNetNS(pid int, fn func() error) error {
+	return ErrNotSupported
+}
+
+// ReadNetLinksPath is only supported on linux.
+func ReadNetLinksPath(nsPath string) ([]NetLink, error) {
+	return nil, ErrNotSupported
+}
+
+// WithNetNSPath is only supported on linux.
+func WithNetNSPath(nsPath string, fn func() error) error {
+	return ErrNotSupported
+}
+
+// ReadNamespaceFile is only supported on linux.
+func ReadNamespaceFile(nsPath string) (string, error) {
+	return "", ErrNotSupported
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"io/ioutil"
+	"os"
+)
+
+// namedNetNSDir is where the network namespaces created by "ip netns" are
+// bind mounted, in the mount namespace of the host.
+const namedNetNSDir = "/var/run/netns"
+
+// ListNamedNetNS returns the path of each network namespace created by
+// "ip netns", keyed by name. The paths are reached through the root
+// filesystem of the host's init process, so they are valid from inside a
+// container running with the host's PID namespace.
+func ListNamedNetNS() (map[string]string, error) {
+	return listNamedNetNS(ProcPath(HostPID, "/root"+namedNetNSDir))
+}
+
+// listNamedNetNS returns the path of each network namespace bind mounted in
+// the given directory, keyed by name. A missing directory has none.
+func listNamedNetNS(dir string) (map[string]string, error) {
+	fis, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	netns := make(map[string]string, len(fis))
+	for _, fi := range fis {
+		netns[fi.Name()] = dir + "/" + fi.Name()
+	}
+	return netns, nil
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"syscall"
+)
+
+func setns(fd uintptr, nstype uintptr) error {
+	if _, _, errno := syscall.RawSyscall(sysSetns, fd, nstype, 0); errno != 0 {
+		return os.NewSyscallError("setns", errno)
+	}
+	return nil
+}
+
+// WithNetNS runs fn inside the network namespace of the process with the
+// given pid. fn runs on its own OS thread which is discarded if it can't be
+// moved back to the original network namespace.
+func WithNetNS(pid int, fn func() error) error {
+	path := "/ns/net"
+	fnErr, err := withNetNS(ProcPath(pid, path), fn)
+	if err != nil {
+		return &StatError{PID: pid, Path: path, Err: err}
+	}
+	return fnErr
+}
+
+// WithNetNSPath runs fn inside the network namespace of the given file, such
+// as a namespace bind mounted in /var/run/netns, the same way as WithNetNS.
+func WithNetNSPath(nsPath string, fn func() error) error {
+	fnErr, err := withNetNS(nsPath, fn)
+	if err != nil {
+		return err
+	}
+	return fnErr
+}
+
+// withNetNS returns the error of fn and, separately, the error entering or
+// leaving the network namespace.
+func withNetNS(nsPath string, fn func() error) (error, error) {
+	ns, err := os.Open(nsPath)
+	if err != nil {
+		return nil, err
+	}
+	defer ns.Close()
+
+	type result struct {
+		fnErr, err error
+	}
+	resCh := make(chan result, 1)
+	go func() {
+		runtime.LockOSThread()
+		origin, err := os.Open(procPath + "/self/task/" + strconv.Itoa(syscall.Gettid()) + "/ns/net")
+		if err != nil {
+			runtime.UnlockOSThread()
+			resCh <- result{err: err}
+			return
+		}
+		defer origin.Close()
+		if err := setns(ns.Fd(), syscall.CLONE_NEWNET); err != nil {
+			runtime.UnlockOSThread()
+			resCh <- result{err: err}
+			return
+		}
+		fnErr := fn()
+		if err := setns(origin.Fd(), syscall.CLONE_NEWNET); err != nil {
+			// Returning with the thread locked terminates it so it
+			// won't be reused in the wrong network namespace.
+			log.Error("Unable to restore network namespace: %s", err)
+			resCh <- result{err: err}
+			return
+		}
+		runtime.UnlockOSThread()
+		resCh <- result{fnErr: fnErr}
+	}()
+	res := <-resCh
+	return res.fnErr, res.err
+}
+
+// ReadNamespaceFile returns the identifier, such as "net:[4026531993]", of
+// the network namespace of the given file, in the same format as
+// ReadNamespace.
+func ReadNamespaceFile(nsPath string) (string, error) {
+	fi, err := os.Stat(nsPath)
+	if err != nil {
+		return "", err
+	}
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return "", ErrNotSupported
+	}
+	return "net:[" + strconv.FormatUint(uint64(st.Ino), 10) + "]", nil
+}
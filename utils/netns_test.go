@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestListNamedNetNS(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"blue", "red"} {
+		f, err := os.Create(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		f.Close()
+	}
+	netns, err := listNamedNetNS(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(netns) != 2 || netns["blue"] != dir+"/blue" || netns["red"] != dir+"/red" {
+		t.Errorf("named network namespaces:\ngot  %v", netns)
+	}
+	netns, err = listNamedNetNS(filepath.Join(dir, "missing"))
+	if err != nil || len(netns) != 0 {
+		t.Errorf("named network namespaces of missing directory:\ngot  %v %v\nwant none", netns, err)
+	}
+	if _, err := listNamedNetNS(filepath.Join(dir, "blue")); err == nil {
+		t.Errorf("file instead of directory should return an error")
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ProcPressureDir is the directory with the Pressure Stall Information of the
+// whole system.
+const ProcPressureDir = procPath + "/pressure"
+
+// ReadPressure parses a Pressure Stall Information file, such as
+// /proc/pressure/cpu or cpu.pressure of a cgroup v2, and returns its values
+// keyed by "some" or "full" and by key. The avg10, avg60 and avg300 averages
+// are returned in hundredths of a percent, which is the precision reported by
+// the kernel, and total in microseconds.
+func ReadPressure(path string) (map[string]map[string]int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parsePressure(bufio.NewScanner(f))
+}
+
+func parsePressure(s *bufio.Scanner) (map[string]map[string]int64, error) {
+	pressure := map[string]map[string]int64{}
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		values := map[string]int64{}
+		for _, field := range fields[1:] {
+			kv := strings.SplitN(field, "=", 2)
+			if len(kv) != 2 {
+				return nil, fmt.Errorf("malformed value in '%s'", s.Text())
+			}
+			if kv[0] == "total" {
+				intVal, err := strconv.ParseInt(kv[1], 10, 64)
+				if err != nil {
+					return nil, fmt.Errorf("malformed value in '%s'", s.Text())
+				}
+				values[kv[0]] = intVal
+				continue
+			}
+			floatVal, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed value in '%s'", s.Text())
+			}
+			values[kv[0]] = int64(math.Floor(floatVal*100 + 0.5))
+		}
+		pressure[fields[0]] = values
+	}
+	return pressure, s.Err()
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParsePressure(t *testing.T) {
+	pressureStr := `some avg10=1.23 avg60=0.50 avg300=0.07 total=123456
+full avg10=0.00 avg60=0.00 avg300=0.00 total=42
+`
+	pressure, err := parsePressure(bufio.NewScanner(strings.NewReader(pressureStr)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]map[string]int64{
+		"some": {"avg10": 123, "avg60": 50, "avg300": 7, "total": 123456},
+		"full": {"avg10": 0, "avg60": 0, "avg300": 0, "total": 42},
+	}
+	for scope, values := range want {
+		for key, value := range values {
+			if got := pressure[scope][key]; got != value {
+				t.Errorf("%s %s:\ngot  %d\nwant %d", scope, key, got, value)
+			}
+		}
+	}
+	if _, err := parsePressure(bufio.NewScanner(strings.NewReader("some avg10\n"))); err == nil {
+		t.Errorf("malformed line should return an error")
+	}
+}
@@ -0,0 +1,192 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unsafe"
+)
+
+const (
+	// atClkTck is the key of the number of clock ticks per second in the
+	// auxiliary vector, see getauxval(3).
+	atClkTck = 17
+	// defaultUserHZ is the number of clock ticks per second on all Linux
+	// architectures but alpha, used if the auxiliary vector can't be read.
+	defaultUserHZ = 100
+)
+
+// userHZ is the number of clock ticks per second used by the kernel to report
+// the CPU time of processes, as given by the kernel in the auxiliary vector of
+// docker-collector.
+var userHZ = readUserHZ()
+
+// ProcessStat holds the statistics of a process read from /proc/<pid>.
+type ProcessStat struct {
+	PID     int
+	Command string
+	Threads int64
+	// RSS is the resident set size in bytes.
+	RSS int64
+	// CPUTime is the user and system CPU time in nanoseconds.
+	CPUTime int64
+	// FDs is the number of open file descriptors.
+	FDs int64
+	// FDLimit is the soft RLIMIT_NOFILE, math.MaxInt64 if unlimited.
+	FDLimit int64
+}
+
+// ListPIDs returns the PIDs of all processes visible in /proc.
+func ListPIDs() ([]int, error) {
+	f, err := os.Open(procPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	names, err := f.Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, name := range names {
+		if pid, err := strconv.Atoi(name); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// ReadNamespace returns the identifier, such as "net:[4026531993]", of the
+// namespace of the given type of the process with the given pid.
+func ReadNamespace(pid int, nsType string) (string, error) {
+	path := "/ns/" + nsType
+	ns, err := os.Readlink(ProcPath(pid, path))
+	if err != nil {
+		return "", &StatError{PID: pid, Path: path, Err: err}
+	}
+	return ns, nil
+}
+
+// ReadCgroupProcs returns the PIDs of the processes of the cgroup directory
+// dir.
+func ReadCgroupProcs(dir string) ([]int, error) {
+	b, err := ioutil.ReadFile(dir + "/cgroup.procs")
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, field := range strings.Fields(string(b)) {
+		pid, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, err
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
+
+// ReadProcessStat returns the statistics of the process with the given pid.
+// The number of open file descriptors is only available with enough
+// privileges over the process and is left as 0 otherwise.
+func ReadProcessStat(pid int) (ProcessStat, error) {
+	ps := ProcessStat{PID: pid, FDLimit: math.MaxInt64}
+	path := "/stat"
+	b, err := ioutil.ReadFile(ProcPath(pid, path))
+	if err != nil {
+		return ps, &StatError{PID: pid, Path: path, Err: err}
+	}
+	if err := parseProcessStat(string(b), &ps); err != nil {
+		return ps, &StatError{PID: pid, Path: path, Err: err}
+	}
+	if fd, err := os.Open(ProcPath(pid, "/fd")); err == nil {
+		names, _ := fd.Readdirnames(-1)
+		fd.Close()
+		ps.FDs = int64(len(names))
+	}
+	if limits, err := os.Open(ProcPath(pid, "/limits")); err == nil {
+		ps.FDLimit = parseFDLimit(bufio.NewScanner(limits))
+		limits.Close()
+	}
+	return ps, nil
+}
+
+func parseProcessStat(stat string, ps *ProcessStat) error {
+	// The command is between parentheses and might have spaces
+	start, end := strings.Index(stat, "("), strings.LastIndex(stat, ")")
+	if start == -1 || end < start {
+		return fmt.Errorf("malformed stat '%s'", stat)
+	}
+	ps.Command = stat[start+1 : end]
+	// Fields after the command, starting with the state (field 3)
+	fields := strings.Fields(stat[end+1:])
+	if len(fields) < 22 {
+		return fmt.Errorf("malformed stat '%s'", stat)
+	}
+	values := map[int]int64{}
+	for _, field := range []int{14, 15, 20, 24} {
+		intVal, err := strconv.ParseInt(fields[field-3], 10, 64)
+		if err != nil {
+			return fmt.Errorf("malformed field %d of stat '%s'", field, stat)
+		}
+		values[field] = intVal
+	}
+	ps.CPUTime = (values[14] + values[15]) * (1e9 / userHZ)
+	ps.Threads = values[20]
+	ps.RSS = values[24] * int64(os.Getpagesize())
+	return nil
+}
+
+func readUserHZ() int64 {
+	b, err := ioutil.ReadFile(procPath + "/self/auxv")
+	if err != nil {
+		return defaultUserHZ
+	}
+	if hz := parseAuxvClockTicks(b, int(unsafe.Sizeof(uintptr(0)))); hz > 0 {
+		return hz
+	}
+	return defaultUserHZ
+}
+
+// parseAuxvClockTicks returns the AT_CLKTCK value of the given auxiliary
+// vector, made of key and value pairs of words of the given size, or 0 if it
+// isn't found.
+func parseAuxvClockTicks(b []byte, wordSize int) int64 {
+	for i := 0; i+2*wordSize <= len(b); i += 2 * wordSize {
+		var key, value uint64
+		if wordSize == 8 {
+			key, value = nativeEndian.Uint64(b[i:]), nativeEndian.Uint64(b[i+8:])
+		} else {
+			key, value = uint64(nativeEndian.Uint32(b[i:])), uint64(nativeEndian.Uint32(b[i+4:]))
+		}
+		switch key {
+		case 0:
+			// AT_NULL ends the vector
+			return 0
+		case atClkTck:
+			return int64(value)
+		}
+	}
+	return 0
+}
+
+func parseFDLimit(s *bufio.Scanner) int64 {
+	for s.Scan() {
+		line := s.Text()
+		if !strings.HasPrefix(line, "Max open files") {
+			continue
+		}
+		fields := strings.Fields(strings.TrimPrefix(line, "Max open files"))
+		if len(fields) == 0 {
+			break
+		}
+		if intVal, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			return intVal
+		}
+		break
+	}
+	return math.MaxInt64
+}
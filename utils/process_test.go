@@ -0,0 +1,46 @@
+package utils
+
+import (
+	"os"
+	"testing"
+)
+
+func TestParseProcessStat(t *testing.T) {
+	stat := "42 (my (app) x) S 1 42 42 0 -1 4194304 82 0 0 0 150 50 0 0 20 0 7 0 135180 2703360 309 18446744073709551615 0\n"
+	var ps ProcessStat
+	if err := parseProcessStat(stat, &ps); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if ps.Command != "my (app) x" {
+		t.Errorf("command:\ngot  %q\nwant %q", ps.Command, "my (app) x")
+	}
+	if ps.CPUTime != 200*(1e9/userHZ) || ps.Threads != 7 || ps.RSS != 309*int64(os.Getpagesize()) {
+		t.Errorf("unexpected values: %+v", ps)
+	}
+}
+
+func TestParseAuxvClockTicks(t *testing.T) {
+	// AT_PAGESZ, AT_CLKTCK and AT_NULL
+	entries := []uint64{6, 4096, atClkTck, 250, 0, 0}
+	auxv64 := make([]byte, len(entries)*8)
+	auxv32 := make([]byte, len(entries)*4)
+	for i, entry := range entries {
+		nativeEndian.PutUint64(auxv64[i*8:], entry)
+		nativeEndian.PutUint32(auxv32[i*4:], uint32(entry))
+	}
+	if hz := parseAuxvClockTicks(auxv64, 8); hz != 250 {
+		t.Errorf("clock ticks of 64 bits auxv:\ngot  %d\nwant %d", hz, 250)
+	}
+	if hz := parseAuxvClockTicks(auxv32, 4); hz != 250 {
+		t.Errorf("clock ticks of 32 bits auxv:\ngot  %d\nwant %d", hz, 250)
+	}
+	if hz := parseAuxvClockTicks(auxv64[:2*8], 8); hz != 0 {
+		t.Errorf("clock ticks of auxv without AT_CLKTCK:\ngot  %d\nwant %d", hz, 0)
+	}
+	if hz := parseAuxvClockTicks(append(auxv64[4*8:], auxv64[:4*8]...), 8); hz != 0 {
+		t.Errorf("entries after AT_NULL should be ignored:\ngot  %d\nwant %d", hz, 0)
+	}
+	if userHZ <= 0 {
+		t.Errorf("invalid clock ticks per second: %d", userHZ)
+	}
+}
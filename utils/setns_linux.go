@@ -0,0 +1,8 @@
+//go:build linux && !amd64 && !386
+// +build linux,!amd64,!386
+
+package utils
+
+import "syscall"
+
+const sysSetns = syscall.SYS_SETNS
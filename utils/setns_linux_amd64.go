@@ -0,0 +1,5 @@
+package utils
+
+// sysSetns is the setns(2) system call number, missing from the syscall
+// package on amd64.
+const sysSetns = 308
@@ -0,0 +1,69 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadNetSNMP parses a file with pairs of header and value lines per
+// protocol, such as /proc/<pid>/net/snmp or /proc/<pid>/net/netstat, of the
+// network namespace of the process with the given pid. Values are keyed by
+// protocol and by counter name.
+func ReadNetSNMP(pid int, file string) (map[string]map[string]int64, error) {
+	path := "/net/" + file
+	f, err := os.Open(ProcPath(pid, path))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	defer f.Close()
+	snmp, err := parseNetSNMP(bufio.NewScanner(f))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	return snmp, nil
+}
+
+func parseNetSNMP(s *bufio.Scanner) (map[string]map[string]int64, error) {
+	snmp := map[string]map[string]int64{}
+	for s.Scan() {
+		header := strings.Fields(s.Text())
+		if !s.Scan() {
+			break
+		}
+		values := strings.Fields(s.Text())
+		if len(header) == 0 || len(header) != len(values) || header[0] != values[0] {
+			return nil, fmt.Errorf("malformed lines for '%s'", strings.Join(header, " "))
+		}
+		protocol := strings.TrimSuffix(header[0], ":")
+		counters := make(map[string]int64, len(header)-1)
+		for i := 1; i < len(header); i++ {
+			intVal, err := strconv.ParseInt(values[i], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("malformed value of %s %s: %s", protocol, header[i], err)
+			}
+			counters[header[i]] = intVal
+		}
+		snmp[protocol] = counters
+	}
+	return snmp, s.Err()
+}
+
+// ReadNetSNMP6 parses /proc/<pid>/net/snmp6 of the network namespace of the
+// process with the given pid. Values are keyed by counter name, which
+// includes the protocol as prefix, such as Udp6InErrors.
+func ReadNetSNMP6(pid int) (map[string]int64, error) {
+	path := "/net/snmp6"
+	f, err := os.Open(ProcPath(pid, path))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	defer f.Close()
+	snmp6, err := parseKeyValues(bufio.NewScanner(f))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	return snmp6, nil
+}
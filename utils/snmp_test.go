@@ -0,0 +1,61 @@
+package utils
+
+import (
+	"bufio"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseNetSNMP(t *testing.T) {
+	snmpStr := `Tcp: RtoAlgorithm RtoMin ActiveOpens RetransSegs
+Tcp: 1 200 12 3
+Udp: InDatagrams InErrors
+Udp: 20 1
+`
+	snmp, err := parseNetSNMP(bufio.NewScanner(strings.NewReader(snmpStr)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if snmp["Tcp"]["ActiveOpens"] != 12 || snmp["Tcp"]["RetransSegs"] != 3 || snmp["Udp"]["InErrors"] != 1 {
+		t.Errorf("unexpected values: %v", snmp)
+	}
+	if _, err := parseNetSNMP(bufio.NewScanner(strings.NewReader("Tcp: A B\nTcp: 1\n"))); err == nil {
+		t.Errorf("malformed lines should return an error")
+	}
+}
+
+func TestParseNetSNMP6(t *testing.T) {
+	snmp6Str := `Ip6InReceives                   	5
+Udp6InDatagrams                 	20
+Udp6InErrors                    	2
+Udp6RcvbufErrors                	1
+`
+	snmp6, err := parseKeyValues(bufio.NewScanner(strings.NewReader(snmp6Str)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if snmp6["Udp6InErrors"] != 2 || snmp6["Udp6RcvbufErrors"] != 1 || len(snmp6) != 4 {
+		t.Errorf("unexpected values: %v", snmp6)
+	}
+	if _, err := parseKeyValues(bufio.NewScanner(strings.NewReader("Udp6InErrors x\n"))); err == nil {
+		t.Errorf("malformed lines should return an error")
+	}
+}
+
+func TestReadNetSNMP6(t *testing.T) {
+	pid := os.Getpid()
+	if _, err := os.Stat(ProcPath(pid, "/net/snmp6")); err != nil {
+		t.Skipf("IPv6 counters not available: %s", err)
+	}
+	snmp6, err := ReadNetSNMP6(pid)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := snmp6["Udp6InErrors"]; !ok {
+		t.Errorf("missing Udp6InErrors: %v", snmp6)
+	}
+	if _, err := ReadNetSNMP6(-1); err == nil {
+		t.Errorf("missing processes should return an error")
+	}
+}
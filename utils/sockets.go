@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Socket is an entry of /proc/<pid>/net/{tcp,tcp6,udp,udp6}.
+type Socket struct {
+	LocalIP    net.IP
+	LocalPort  int
+	RemoteIP   net.IP
+	RemotePort int
+	State      int
+}
+
+// TCP states of connections, as stored in the socket tables.
+const (
+	tcpEstablished = 0x01
+	tcpSynSent     = 0x02
+	tcpCloseWait   = 0x08
+)
+
+// Connected returns true if the socket is a TCP connection to a remote peer,
+// established, being established or being closed by the peer. Sockets
+// without remote address, such as listening ones, are not connected.
+func (sock Socket) Connected() bool {
+	switch sock.State {
+	case tcpEstablished, tcpSynSent, tcpCloseWait:
+	default:
+		return false
+	}
+	return sock.RemotePort != 0 && sock.RemoteIP != nil && !sock.RemoteIP.IsUnspecified()
+}
+
+// ReadNetSockets parses the given socket table file, tcp, tcp6, udp or udp6,
+// of the network namespace of the process with the given pid.
+func ReadNetSockets(pid int, file string) ([]Socket, error) {
+	path := "/net/" + file
+	f, err := os.Open(ProcPath(pid, path))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	defer f.Close()
+	sockets, err := parseNetSockets(bufio.NewScanner(f))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	return sockets, nil
+}
+
+func parseNetSockets(s *bufio.Scanner) ([]Socket, error) {
+	var sockets []Socket
+	// Skip header
+	s.Scan()
+	for s.Scan() {
+		fields := strings.Fields(s.Text())
+		if len(fields) < 4 {
+			return nil, fmt.Errorf("malformed socket line '%s'", s.Text())
+		}
+		var (
+			sock Socket
+			err  error
+		)
+		if sock.LocalIP, sock.LocalPort, err = parseHexAddr(fields[1]); err != nil {
+			return nil, err
+		}
+		if sock.RemoteIP, sock.RemotePort, err = parseHexAddr(fields[2]); err != nil {
+			return nil, err
+		}
+		state, err := strconv.ParseInt(fields[3], 16, 32)
+		if err != nil {
+			return nil, fmt.Errorf("malformed socket state '%s'", fields[3])
+		}
+		sock.State = int(state)
+		sockets = append(sockets, sock)
+	}
+	return sockets, s.Err()
+}
+
+// parseHexAddr parses an "address:port" of a socket table, where the address
+// is stored as 32 bit words in host byte order.
+func parseHexAddr(hexAddr string) (net.IP, int, error) {
+	i := strings.Index(hexAddr, ":")
+	if i == -1 {
+		return nil, 0, fmt.Errorf("malformed address '%s'", hexAddr)
+	}
+	b, err := hex.DecodeString(hexAddr[:i])
+	if err != nil || (len(b) != net.IPv4len && len(b) != net.IPv6len) {
+		return nil, 0, fmt.Errorf("malformed address '%s'", hexAddr)
+	}
+	ip := make(net.IP, len(b))
+	for w := 0; w < len(b); w += 4 {
+		word := nativeEndian.Uint32(b[w:])
+		ip[w], ip[w+1], ip[w+2], ip[w+3] = byte(word>>24), byte(word>>16), byte(word>>8), byte(word)
+	}
+	port, err := strconv.ParseUint(hexAddr[i+1:], 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("malformed port '%s'", hexAddr)
+	}
+	return ip, int(port), nil
+}
+
+// CountNetUnixSockets returns the number of unix sockets of the network
+// namespace of the process with the given pid.
+func CountNetUnixSockets(pid int) (int, error) {
+	path := "/net/unix"
+	f, err := os.Open(ProcPath(pid, path))
+	if err != nil {
+		return 0, &StatError{PID: pid, Path: path, Err: err}
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	count := -1
+	for s.Scan() {
+		count++
+	}
+	if count < 0 {
+		count = 0
+	}
+	return count, s.Err()
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseNetSockets(t *testing.T) {
+	tcpStr := `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 0100007F:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1057 1 0 100 0 0 10 0
+   1: 0100007F:1F90 0200000A:D431 08 00000000:00000000 00:00000000 00000000     0        0 1058 1 0 100 0 0 10 0
+`
+	sockets, err := parseNetSockets(bufio.NewScanner(strings.NewReader(tcpStr)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(sockets) != 2 {
+		t.Fatalf("number of sockets:\ngot  %d\nwant %d", len(sockets), 2)
+	}
+	if nativeEndian.Uint16([]byte{1, 0}) != 1 {
+		t.Skip("addresses of the test are little endian")
+	}
+	if got := sockets[0].LocalIP.String(); got != "127.0.0.1" || sockets[0].LocalPort != 8080 || sockets[0].State != 0x0A {
+		t.Errorf("unexpected listening socket: %+v", sockets[0])
+	}
+	if got := sockets[1].RemoteIP.String(); got != "10.0.0.2" || sockets[1].RemotePort != 54321 || sockets[1].State != 0x08 {
+		t.Errorf("unexpected connected socket: %+v", sockets[1])
+	}
+}
+
+func TestSocketConnected(t *testing.T) {
+	const header = "  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n"
+	tests := []struct {
+		name      string
+		line      string
+		connected bool
+	}{
+		{"established", "0: 0100007F:1F90 0200000A:D431 01", true},
+		{"syn sent", "0: 0100007F:D431 0200000A:1F90 02", true},
+		{"close wait", "0: 0100007F:1F90 0200000A:D431 08", true},
+		{"syn recv", "0: 0100007F:1F90 0200000A:D431 03", false},
+		{"time wait", "0: 0100007F:1F90 0200000A:D431 06", false},
+		{"listen", "0: 0100007F:1F90 00000000:0000 0A", false},
+		{"unspecified remote", "0: 0100007F:1F90 00000000:0000 01", false},
+		{"unspecified IPv6 remote", "0: 00000000000000000000000001000000:1F90 00000000000000000000000000000000:0000 01", false},
+		{"IPv6 remote", "0: 00000000000000000000000001000000:1F90 0000000000000000FFFF00000200000A:D431 01", true},
+	}
+	for _, tt := range tests {
+		sockets, err := parseNetSockets(bufio.NewScanner(strings.NewReader(header + tt.line + "\n")))
+		if err != nil || len(sockets) != 1 {
+			t.Fatalf("%s: unexpected sockets %v: %v", tt.name, sockets, err)
+		}
+		if got := sockets[0].Connected(); got != tt.connected {
+			t.Errorf("connected of %s:\ngot  %t\nwant %t", tt.name, got, tt.connected)
+		}
+	}
+}
@@ -1,31 +1,184 @@
 package utils
 
 import (
+	"bufio"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"strconv"
 	"strings"
 
-	ue "github.com/cilium-team/docker-collector/utils/executable"
-
 	"github.com/cilium-team/docker-collector/Godeps/_workspace/src/github.com/op/go-logging"
 )
 
 const procPath = "/proc"
 
+// HostPID is the pid of the init process of the host, whose namespaces are
+// the ones of the host when running with the host's PID namespace.
+const HostPID = 1
+
 var log = logging.MustGetLogger("docker-collector")
 
+// ErrEmptyStat is returned when a statistic file exists but has no content.
+var ErrEmptyStat = errors.New("empty statistic")
+
+// StatError records an error while reading a statistic of the process with the
+// given PID.
+type StatError struct {
+	PID  int
+	Path string
+	Err  error
+}
+
+func (e *StatError) Error() string {
+	return fmt.Sprintf("unable to read '%s' of pid %d: %s", e.Path, e.PID, e.Err)
+}
+
+// IsPermission returns true if the given error reports that the statistic
+// can't be read with the privileges of the collector, such as the namespaces
+// of containers of other users when not running privileged.
+func IsPermission(err error) bool {
+	switch e := err.(type) {
+	case *StatError:
+		return os.IsPermission(e.Err)
+	}
+	return os.IsPermission(err)
+}
+
+// ProcPath returns the path of the given fullpath under /proc/<pid>.
+func ProcPath(pid int, fullpath string) string {
+	return procPath + "/" + strconv.Itoa(pid) + fullpath
+}
+
+// containerPath returns the path of the given fullpath inside the root
+// filesystem of the process with the given pid.
+func containerPath(pid int, fullpath string) string {
+	return ProcPath(pid, "/root"+fullpath)
+}
+
+// ReadContainerFromProc returns the content, without the trailing new lines,
+// of the file in fullpath inside the root filesystem of the process with the
+// given pid.
 func ReadContainerFromProc(pid int, fullpath string) (string, error) {
-	b, err := ue.ExecShCommand(`cat ` + procPath + `/` + strconv.Itoa(pid) + `/root` + fullpath)
+	b, err := ioutil.ReadFile(containerPath(pid, fullpath))
 	if err != nil {
-		return "", err
+		return "", &StatError{PID: pid, Path: fullpath, Err: err}
 	}
 	bstr := strings.TrimRight(string(b), "\n")
-	if strings.Contains(bstr, "Cannot run exec command") ||
-		strings.Contains(bstr, "No such exec") ||
-		strings.Contains(bstr, "Failed") ||
-		strings.Contains(bstr, "invalid argument") ||
-		bstr == "" {
-		return "", errors.New("Invalid path")
+	if bstr == "" {
+		return "", &StatError{PID: pid, Path: fullpath, Err: ErrEmptyStat}
 	}
 	return bstr, nil
 }
+
+// ReadInt64FromProc returns the value of the file in fullpath inside the root
+// filesystem of the process with the given pid.
+func ReadInt64FromProc(pid int, fullpath string) (int64, error) {
+	value, err := ReadContainerFromProc(pid, fullpath)
+	if err != nil {
+		return 0, err
+	}
+	intVal, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, &StatError{PID: pid, Path: fullpath, Err: err}
+	}
+	return intVal, nil
+}
+
+// ReadStatsFromProc reads the statistics with the given names from the
+// directory dir inside the root filesystem of the process with the given pid.
+// Statistics that can't be read are left out of the returned map.
+func ReadStatsFromProc(pid int, dir string, names []string) (map[string]int64, error) {
+	if _, err := os.Stat(containerPath(pid, dir)); err != nil {
+		return nil, &StatError{PID: pid, Path: dir, Err: err}
+	}
+	values := make(map[string]int64, len(names))
+	for _, name := range names {
+		intVal, err := ReadInt64FromProc(pid, dir+name)
+		if err != nil {
+			log.Debug("%s", err)
+			continue
+		}
+		values[name] = intVal
+	}
+	return values, nil
+}
+
+// ListContainerDir returns the names of the entries of the directory in
+// fullpath inside the root filesystem of the process with the given pid.
+func ListContainerDir(pid int, fullpath string) ([]string, error) {
+	fis, err := ioutil.ReadDir(containerPath(pid, fullpath))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: fullpath, Err: err}
+	}
+	names := make([]string, 0, len(fis))
+	for _, fi := range fis {
+		names = append(names, fi.Name())
+	}
+	return names, nil
+}
+
+// netDevStatsNames are the sysfs statistic names of each column of
+// /proc/<pid>/net/dev.
+var netDevStatsNames = []string{
+	"rx_bytes",
+	"rx_packets",
+	"rx_errors",
+	"rx_dropped",
+	"rx_fifo_errors",
+	"rx_frame_errors",
+	"rx_compressed",
+	"multicast",
+	"tx_bytes",
+	"tx_packets",
+	"tx_errors",
+	"tx_dropped",
+	"tx_fifo_errors",
+	"collisions",
+	"tx_carrier_errors",
+	"tx_compressed",
+}
+
+// ReadNetDev parses /proc/<pid>/net/dev, which reflects the network namespace
+// of the process with the given pid, and returns the statistics of each
+// interface keyed by interface name and by their sysfs statistic name.
+func ReadNetDev(pid int) (map[string]map[string]int64, error) {
+	path := "/net/dev"
+	f, err := os.Open(ProcPath(pid, path))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	defer f.Close()
+	netDev, err := parseNetDev(bufio.NewScanner(f))
+	if err != nil {
+		return nil, &StatError{PID: pid, Path: path, Err: err}
+	}
+	return netDev, nil
+}
+
+func parseNetDev(s *bufio.Scanner) (map[string]map[string]int64, error) {
+	netDev := map[string]map[string]int64{}
+	for s.Scan() {
+		line := s.Text()
+		i := strings.Index(line, ":")
+		if i == -1 {
+			// Header lines
+			continue
+		}
+		fields := strings.Fields(line[i+1:])
+		if len(fields) != len(netDevStatsNames) {
+			return nil, fmt.Errorf("unexpected number of columns in '%s'", line)
+		}
+		values := make(map[string]int64, len(fields))
+		for j, field := range fields {
+			intVal, err := strconv.ParseInt(field, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			values[netDevStatsNames[j]] = intVal
+		}
+		netDev[strings.TrimSpace(line[:i])] = values
+	}
+	return netDev, s.Err()
+}
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestParseNetDev(t *testing.T) {
+	netDevStr := `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo:     336       4    0    0    0     0          0         0      336       4    0    0    0     0       0          0
+  eth0:   10528      97    1    2    0     0          0         3     1296      16    0    0    0     5       0          0
+`
+	netDev, err := parseNetDev(bufio.NewScanner(strings.NewReader(netDevStr)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(netDev) != 2 {
+		t.Fatalf("number of interfaces:\ngot  %d\nwant %d", len(netDev), 2)
+	}
+	want := map[string]int64{
+		"rx_bytes":   10528,
+		"rx_packets": 97,
+		"rx_errors":  1,
+		"rx_dropped": 2,
+		"multicast":  3,
+		"tx_bytes":   1296,
+		"collisions": 5,
+	}
+	for name, value := range want {
+		if got := netDev["eth0"][name]; got != value {
+			t.Errorf("eth0/%s:\ngot  %d\nwant %d", name, got, value)
+		}
+	}
+
+	if _, err := parseNetDev(bufio.NewScanner(strings.NewReader("eth0: 1 2 3\n"))); err == nil {
+		t.Errorf("malformed line should return an error")
+	}
+}
+
+func TestIsPermission(t *testing.T) {
+	pathErr := &os.PathError{Op: "open", Path: "/proc/1/ns/net", Err: syscall.EACCES}
+	tests := []struct {
+		name       string
+		err        error
+		permission bool
+	}{
+		{"nil", nil, false},
+		{"permission", pathErr, true},
+		{"StatError", &StatError{PID: 1, Path: "/ns/net", Err: pathErr}, true},
+		{"not exist", &StatError{PID: 1, Path: "/net/dev", Err: os.ErrNotExist}, false},
+		{"empty", &StatError{PID: 1, Path: "/net/dev", Err: ErrEmptyStat}, false},
+		{"other", errors.New("other"), false},
+	}
+	for _, tt := range tests {
+		if got := IsPermission(tt.err); got != tt.permission {
+			t.Errorf("IsPermission of %s:\ngot  %t\nwant %t", tt.name, got, tt.permission)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ReadLoadAvg parses /proc/loadavg and returns the load averages over 1, 5 and
+// 15 minutes, keyed by load1, load5 and load15, in hundredths, and the number
+// of runnable and of existing tasks, keyed by procs_running and procs_total.
+func ReadLoadAvg() (map[string]int64, error) {
+	b, err := ioutil.ReadFile(procPath + "/loadavg")
+	if err != nil {
+		return nil, err
+	}
+	return parseLoadAvg(string(b))
+}
+
+func parseLoadAvg(s string) (map[string]int64, error) {
+	// 0.20 0.18 0.12 1/80 11206
+	fields := strings.Fields(s)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("malformed loadavg '%s'", s)
+	}
+	values := map[string]int64{}
+	for i, name := range []string{"load1", "load5", "load15"} {
+		floatVal, err := strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed loadavg '%s'", s)
+		}
+		values[name] = int64(math.Floor(floatVal*100 + 0.5))
+	}
+	procs := strings.SplitN(fields[3], "/", 2)
+	if len(procs) != 2 {
+		return nil, fmt.Errorf("malformed loadavg '%s'", s)
+	}
+	for i, name := range []string{"procs_running", "procs_total"} {
+		intVal, err := strconv.ParseInt(procs[i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed loadavg '%s'", s)
+		}
+		values[name] = intVal
+	}
+	return values, nil
+}
+
+// ReadMemInfo parses /proc/meminfo and returns its values keyed by their name,
+// such as MemTotal or SwapFree. Values reported in kB are returned in bytes.
+func ReadMemInfo() (map[string]int64, error) {
+	f, err := os.Open(procPath + "/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseMemInfo(bufio.NewScanner(f))
+}
+
+func parseMemInfo(s *bufio.Scanner) (map[string]int64, error) {
+	values := map[string]int64{}
+	for s.Scan() {
+		// MemTotal:        8055128 kB
+		i := strings.Index(s.Text(), ":")
+		if i == -1 {
+			continue
+		}
+		fields := strings.Fields(s.Text()[i+1:])
+		if len(fields) == 0 {
+			continue
+		}
+		intVal, err := strconv.ParseInt(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("malformed value in '%s'", s.Text())
+		}
+		if len(fields) == 2 && fields[1] == "kB" {
+			intVal *= 1024
+		}
+		values[s.Text()[:i]] = intVal
+	}
+	return values, s.Err()
+}
+
+// ReadUptime returns the number of seconds since the system booted, as read
+// from /proc/uptime.
+func ReadUptime() (int64, error) {
+	b, err := ioutil.ReadFile(procPath + "/uptime")
+	if err != nil {
+		return 0, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) == 0 {
+		return 0, ErrEmptyStat
+	}
+	floatVal, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(floatVal), nil
+}
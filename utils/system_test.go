@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+func TestParseLoadAvg(t *testing.T) {
+	loadavg, err := parseLoadAvg("0.20 1.85 12.07 3/812 11206\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]int64{
+		"load1":         20,
+		"load5":         185,
+		"load15":        1207,
+		"procs_running": 3,
+		"procs_total":   812,
+	}
+	for key, value := range want {
+		if got := loadavg[key]; got != value {
+			t.Errorf("%s:\ngot  %d\nwant %d", key, got, value)
+		}
+	}
+	if _, err := parseLoadAvg("0.20 0.18\n"); err == nil {
+		t.Errorf("malformed loadavg should return an error")
+	}
+}
+
+func TestParseMemInfo(t *testing.T) {
+	memInfoStr := `MemTotal:        8055128 kB
+MemFree:          412344 kB
+MemAvailable:    5131608 kB
+HugePages_Total:       0
+`
+	memInfo, err := parseMemInfo(bufio.NewScanner(strings.NewReader(memInfoStr)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := map[string]int64{
+		"MemTotal":        8055128 * 1024,
+		"MemFree":         412344 * 1024,
+		"MemAvailable":    5131608 * 1024,
+		"HugePages_Total": 0,
+	}
+	for key, value := range want {
+		if got, ok := memInfo[key]; !ok || got != value {
+			t.Errorf("%s:\ngot  %d\nwant %d", key, got, value)
+		}
+	}
+}